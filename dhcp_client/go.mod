@@ -1,11 +1,26 @@
 module github.com/polarbroadband/rp1/dhcp_client
 
+replace github.com/polarbroadband/rp1/proto => ../proto
+
 go 1.19
 
-require github.com/insomniacslk/dhcp v0.0.0-20221215072855-de60144f33f8
+require (
+	github.com/insomniacslk/dhcp v0.0.0-20221215072855-de60144f33f8
+	github.com/polarbroadband/rp1/proto v0.0.0-00010101000000-000000000000
+	github.com/prometheus/client_golang v1.14.0
+)
 
 require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.1.2 // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/josharian/native v1.1.0 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
+	github.com/prometheus/client_model v0.3.0 // indirect
+	github.com/prometheus/common v0.37.0 // indirect
+	github.com/prometheus/procfs v0.8.0 // indirect
 	github.com/u-root/uio v0.0.0-20221213070652-c3537552635f // indirect
+	golang.org/x/net v0.0.0-20220225172249-27dd8689420f // indirect
 	golang.org/x/sys v0.4.0 // indirect
+	google.golang.org/protobuf v1.28.1 // indirect
 )
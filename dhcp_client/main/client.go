@@ -1,30 +1,270 @@
 package main
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"net"
+	"time"
 
-	"github.com/insomniacslk/dhcp/dhcpv6/client6"
+	"github.com/insomniacslk/dhcp/dhcpv6"
 )
 
-func FourMsgTransaction(client *client6.Client, intf string) {
-	// Exchange runs a Solicit-Advertise-Request-Reply transaction on the
-	// specified network interface, and returns a list of DHCPv6 packets
-	// (a "conversation") and an error if any. Notice that Exchange may
-	// return a non-empty packet list even if there is an error. This is
-	// intended, because the transaction may fail at any point, and we
-	// still want to know what packets were exchanged until then.
-	// A default Solicit packet will be used during the "conversation",
-	// which can be manipulated by using modifiers.
-	conversation, err := client.Exchange(intf)
-
-	// Summary() prints a verbose representation of the exchanged packets.
-	for _, packet := range conversation {
-		log.Print(packet.Summary())
-	}
-	// error handling is done *after* printing, so we still print the
-	// exchanged packets if any, as explained above.
+var (
+	DEFAULT_TIMEOUT = 3 * time.Second
+	// server and relay agent port, answers to a relay agent come back to it
+	SERVER_PORT = dhcpv6.DefaultServerPort
+	CLIENT_PORT = dhcpv6.DefaultClientPort
+
+	ErrNoResponse = errors.New("no response")
+)
+
+// Probe is the client identity and the transport of the test messages, it
+// talks straight to the link or, with Relay set, as a relay agent does
+type Probe struct {
+	Iface  string
+	Server net.IP // unicast server address, the link multicast address if nil
+	Relay  *Relay // simulated relay agent, nil for a client on the link
+
+	DUID     dhcpv6.Duid
+	IAID     [4]byte
+	ServerID *dhcpv6.Duid // server selected, required by Request, Renew, Release and Decline
+	ORO      []dhcpv6.OptionCode
+
+	NA       bool
+	PD       bool
+	PDLen    int          // prefix length hint of the IA_PD
+	Addrs    []net.IP     // addresses held, renewed, released, declined or confirmed
+	Prefixes []*net.IPNet // prefixes held, renewed or released
+
+	RapidCommit  bool
+	ReconfAccept bool
+	Timeout      time.Duration
+}
+
+// NewMessage builds a client message of the type out of the probe identity
+// and IAs
+func (p *Probe) NewMessage(mt dhcpv6.MessageType) (*dhcpv6.Message, error) {
+	msg, err := dhcpv6.NewMessage()
+	if err != nil {
+		return nil, err
+	}
+	msg.MessageType = mt
+	msg.AddOption(dhcpv6.OptClientID(p.DUID))
+	msg.AddOption(dhcpv6.OptElapsedTime(0))
+	if len(p.ORO) > 0 {
+		msg.AddOption(dhcpv6.OptRequestedOption(p.ORO...))
+	}
+	switch mt {
+	case dhcpv6.MessageTypeRequest, dhcpv6.MessageTypeRenew, dhcpv6.MessageTypeRelease, dhcpv6.MessageTypeDecline:
+		if p.ServerID == nil {
+			return nil, fmt.Errorf("%s requires the server DUID", mt)
+		}
+		msg.AddOption(dhcpv6.OptServerID(*p.ServerID))
+	case dhcpv6.MessageTypeInformationRequest:
+		return msg, nil
+	}
+
+	if p.NA || len(p.Addrs) > 0 {
+		ia := &dhcpv6.OptIANA{IaId: p.IAID}
+		for _, a := range p.Addrs {
+			ia.Options.Add(&dhcpv6.OptIAAddress{IPv6Addr: a})
+		}
+		msg.AddOption(ia)
+	}
+	// a Decline or Confirm concerns addresses only
+	if mt != dhcpv6.MessageTypeDecline && mt != dhcpv6.MessageTypeConfirm && (p.PD || len(p.Prefixes) > 0) {
+		ia := &dhcpv6.OptIAPD{IaId: p.IAID}
+		for _, pfx := range p.Prefixes {
+			ia.Options.Add(&dhcpv6.OptIAPrefix{Prefix: pfx})
+		}
+		if len(p.Prefixes) == 0 && p.PDLen > 0 {
+			ia.Options.Add(&dhcpv6.OptIAPrefix{Prefix: &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(p.PDLen, 128)}})
+		}
+		msg.AddOption(ia)
+	}
+	if mt == dhcpv6.MessageTypeSolicit && p.RapidCommit {
+		msg.AddOption(&dhcpv6.OptionGeneric{OptionCode: dhcpv6.OptionRapidCommit})
+	}
+	if p.ReconfAccept && (mt == dhcpv6.MessageTypeSolicit || mt == dhcpv6.MessageTypeRequest || mt == dhcpv6.MessageTypeRenew || mt == dhcpv6.MessageTypeRebind) {
+		msg.AddOption(&dhcpv6.OptionGeneric{OptionCode: dhcpv6.OptionReconfAccept})
+	}
+	return msg, nil
+}
+
+// Exchange sends the message and returns the responses of the same
+// transaction received until the timeout, all of them for a Solicit and the
+// first one otherwise
+func (p *Probe) Exchange(msg *dhcpv6.Message) ([]*dhcpv6.Message, error) {
+	laddr := &net.UDPAddr{IP: net.IPv6unspecified, Port: CLIENT_PORT}
+	if p.Relay != nil {
+		laddr.Port = SERVER_PORT
+	}
+	conn, err := net.ListenUDP("udp6", laddr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	raddr := &net.UDPAddr{IP: p.Server, Port: SERVER_PORT}
+	if p.Server == nil {
+		if p.Relay != nil {
+			return nil, fmt.Errorf("a relay agent requires the server address")
+		}
+		raddr.IP, raddr.Zone = dhcpv6.AllDHCPRelayAgentsAndServers, p.Iface
+	} else if p.Server.IsLinkLocalUnicast() {
+		raddr.Zone = p.Iface
+	}
+	var out dhcpv6.DHCPv6 = msg
+	if p.Relay != nil {
+		if out, err = p.Relay.Forward(msg, p.Iface); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := conn.WriteTo(out.ToBytes(), raddr); err != nil {
+		return nil, err
+	}
+
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = DEFAULT_TIMEOUT
+	}
+	deadline := time.Now().Add(timeout)
+	responses := []*dhcpv6.Message{}
+	buf := make([]byte, 0xffff)
+	for {
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return responses, err
+		}
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			var nerr net.Error
+			if errors.As(err, &nerr) && nerr.Timeout() {
+				break
+			}
+			return responses, err
+		}
+		resp, err := Decapsulate(buf[:n])
+		if err != nil || resp.TransactionID != msg.TransactionID {
+			continue
+		}
+		responses = append(responses, resp)
+		if msg.Type() != dhcpv6.MessageTypeSolicit || resp.Type() == dhcpv6.MessageTypeReply {
+			break
+		}
+	}
+	if len(responses) == 0 {
+		return nil, ErrNoResponse
+	}
+	return responses, nil
+}
+
+// Request runs the Solicit, Advertise, Request and Reply exchange, or the
+// Solicit and Reply one with rapid commit, and returns every message
+func (p *Probe) Request() ([]*dhcpv6.Message, error) {
+	sol, err := p.NewMessage(dhcpv6.MessageTypeSolicit)
+	if err != nil {
+		return nil, err
+	}
+	conversation := []*dhcpv6.Message{sol}
+	responses, err := p.Exchange(sol)
+	if err != nil {
+		return conversation, err
+	}
+	adv := SelectAdvertise(responses)
+	conversation = append(conversation, adv)
+	if adv.Type() == dhcpv6.MessageTypeReply {
+		return conversation, nil
+	}
+
+	// request what the server advertised
+	req := *p
+	req.ServerID = adv.Options.ServerID()
+	if req.ServerID == nil {
+		return conversation, fmt.Errorf("advertise without server identifier")
+	}
+	req.Addrs, req.Prefixes = AdvertisedLeases(adv)
+	if len(req.Addrs) == 0 && len(req.Prefixes) == 0 {
+		return conversation, fmt.Errorf("nothing advertised")
+	}
+	msg, err := req.NewMessage(dhcpv6.MessageTypeRequest)
+	if err != nil {
+		return conversation, err
+	}
+	conversation = append(conversation, msg)
+	responses, err = req.Exchange(msg)
 	if err != nil {
-		log.Fatal(err)
+		return conversation, err
 	}
+	return append(conversation, responses[0]), nil
+}
+
+// SelectAdvertise returns the Reply of a rapid commit, else the Advertise of
+// the highest preference
+func SelectAdvertise(responses []*dhcpv6.Message) *dhcpv6.Message {
+	best, pref := responses[0], -1
+	for _, r := range responses {
+		if r.Type() == dhcpv6.MessageTypeReply {
+			return r
+		}
+		p := 0
+		if opt := r.GetOneOption(dhcpv6.OptionPreference); opt != nil && len(opt.ToBytes()) == 1 {
+			p = int(opt.ToBytes()[0])
+		}
+		if p > pref {
+			best, pref = r, p
+		}
+	}
+	return best
+}
+
+// AdvertisedLeases returns the addresses and prefixes of the IAs of a response
+func AdvertisedLeases(resp *dhcpv6.Message) ([]net.IP, []*net.IPNet) {
+	addrs, prefixes := []net.IP{}, []*net.IPNet{}
+	for _, ia := range resp.Options.IANA() {
+		for _, a := range ia.Options.Addresses() {
+			addrs = append(addrs, a.IPv6Addr)
+		}
+	}
+	for _, ia := range resp.Options.IAPD() {
+		for _, pfx := range ia.Options.Prefixes() {
+			if pfx.Prefix != nil {
+				prefixes = append(prefixes, pfx.Prefix)
+			}
+		}
+	}
+	return addrs, prefixes
+}
+
+// RFC 5007 query types
+const (
+	LQ_QUERY_BY_ADDRESS  = 1
+	LQ_QUERY_BY_CLIENTID = 2
+)
 
+// NewLeaseQuery builds a LEASEQUERY for the bindings of the address, or of
+// the client DUID when addr is nil, on the link, any link if link is nil
+func (p *Probe) NewLeaseQuery(link, addr net.IP, clientID []byte) (*dhcpv6.Message, error) {
+	msg, err := p.NewMessage(dhcpv6.MessageTypeInformationRequest)
+	if err != nil {
+		return nil, err
+	}
+	msg.MessageType = dhcpv6.MessageTypeLeaseQuery
+	if link == nil {
+		link = net.IPv6unspecified
+	}
+	qopts := dhcpv6.Options{}
+	data := []byte{LQ_QUERY_BY_ADDRESS}
+	switch {
+	case addr != nil:
+		qopts.Add(&dhcpv6.OptIAAddress{IPv6Addr: addr})
+	case len(clientID) > 0:
+		data[0] = LQ_QUERY_BY_CLIENTID
+		qopts.Add(&dhcpv6.OptionGeneric{OptionCode: dhcpv6.OptionClientID, OptionData: clientID})
+	default:
+		return nil, fmt.Errorf("leasequery requires an address or a client DUID")
+	}
+	data = append(data, link.To16()...)
+	data = append(data, qopts.ToBytes()...)
+	msg.AddOption(&dhcpv6.OptionGeneric{OptionCode: dhcpv6.OptionLQQuery, OptionData: data})
+	return msg, nil
 }
@@ -1,12 +1,37 @@
 package main
 
 import (
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/iana"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// COMMANDS maps the subcommands to the client message they send
+var COMMANDS = map[string]dhcpv6.MessageType{
+	"solicit":    dhcpv6.MessageTypeSolicit,
+	"request":    dhcpv6.MessageTypeRequest,
+	"renew":      dhcpv6.MessageTypeRenew,
+	"rebind":     dhcpv6.MessageTypeRebind,
+	"release":    dhcpv6.MessageTypeRelease,
+	"decline":    dhcpv6.MessageTypeDecline,
+	"confirm":    dhcpv6.MessageTypeConfirm,
+	"inform":     dhcpv6.MessageTypeInformationRequest,
+	"leasequery": dhcpv6.MessageTypeLeaseQuery,
+}
+
 type Host struct {
 	Name string
 }
@@ -16,7 +41,127 @@ func (h *Host) Healtz(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{"host": h.Name, "status": "ready"})
 }
 
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage: %s <command> [flags]
+
+commands:
+  solicit     Solicit and print the Advertise of every server
+  request     full Solicit, Advertise, Request and Reply exchange
+  renew       Renew the -addr and -prefix bindings with server -server-id
+  rebind      Rebind the -addr and -prefix bindings with any server
+  release     Release the -addr and -prefix bindings to server -server-id
+  decline     Decline the -addr addresses to server -server-id
+  confirm     Confirm the -addr addresses are on link
+  inform      Information-request the configuration options
+  leasequery  query the bindings of -addr or -query-duid, -server required
+  monitor     run request and release every -interval, serve the results
+              as metrics on /metrics and /healtz
+  serve       serve /healtz on :8080
+
+run '%s <command> -h' for the flags
+`, os.Args[0], os.Args[0])
+	os.Exit(2)
+}
+
 func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd := os.Args[1]
+	switch cmd {
+	case "serve":
+		serve()
+		return
+	case "monitor":
+		monitor()
+		return
+	}
+	mt, ok := COMMANDS[cmd]
+	if !ok {
+		usage()
+	}
+
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	newProbe := probeFlags(fs)
+	link := fs.String("link", "", "link address of the leasequery, any link if empty")
+	queryDuid := fs.String("query-duid", "", "client DUID to leasequery, hex")
+	asJSON := fs.Bool("json", false, "print JSON instead of the message summaries")
+	fs.Parse(os.Args[2:])
+	p := newProbe()
+
+	var msg *dhcpv6.Message
+	var err error
+	conversation := []*dhcpv6.Message{}
+	switch mt {
+	case dhcpv6.MessageTypeRequest:
+		conversation, err = p.Request()
+	case dhcpv6.MessageTypeLeaseQuery:
+		var l, a net.IP
+		var cid []byte
+		if *link != "" {
+			if l = net.ParseIP(*link); l == nil {
+				fail("invalid link address %s", *link)
+			}
+		}
+		if len(p.Addrs) > 0 {
+			a = p.Addrs[0]
+		}
+		if cid, err = hex.DecodeString(*queryDuid); err != nil {
+			fail("invalid query DUID %s: %v", *queryDuid, err)
+		}
+		if p.Server == nil {
+			fail("leasequery requires -server")
+		}
+		if msg, err = p.NewLeaseQuery(l, a, cid); err != nil {
+			fail("%v", err)
+		}
+	default:
+		if msg, err = p.NewMessage(mt); err != nil {
+			fail("%v", err)
+		}
+	}
+	if msg != nil {
+		conversation = append(conversation, msg)
+		var responses []*dhcpv6.Message
+		responses, err = p.Exchange(msg)
+		conversation = append(conversation, responses...)
+	}
+	Print(os.Stdout, *asJSON, conversation, err)
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// monitor runs the probe periodically and serves its results
+func monitor() {
+	fs := flag.NewFlagSet("monitor", flag.ExitOnError)
+	newProbe := probeFlags(fs)
+	interval := fs.Duration("interval", DEFAULT_INTERVAL, "time between probes")
+	window := fs.Int("window", DEFAULT_WINDOW, "number of recent probes /healtz is ready on if any succeeded")
+	listen := fs.String("listen", ":8080", "address of the /metrics and /healtz HTTP server")
+	fs.Parse(os.Args[2:])
+	p := newProbe()
+	if *window < 1 {
+		fail("invalid window %v", *window)
+	}
+
+	hostName, err := os.Hostname()
+	if err != nil {
+		log.Fatal("unable to get hostname")
+	}
+	reg := prometheus.NewRegistry()
+	m := NewMonitor(reg, hostName, p, *interval, *window)
+	go m.Run()
+
+	http.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg}))
+	http.HandleFunc("/healtz", m.Healtz)
+	if http.ListenAndServe(*listen, nil) != nil {
+		log.Fatal("unable to start http server")
+	}
+}
+
+// serve keeps the pod alive and ready, the probes run through kubectl exec
+func serve() {
 	hostName, err := os.Hostname()
 	if err != nil {
 		log.Fatal("unable to get hostname")
@@ -28,3 +173,159 @@ func main() {
 		log.Fatal("unable to start http server")
 	}
 }
+
+// probeFlags defines the flags of the probe identity and transport on the
+// flag set, the returned function builds the probe once they are parsed
+func probeFlags(fs *flag.FlagSet) func() *Probe {
+	iface := fs.String("i", "eth1", "interface to send from")
+	server := fs.String("server", "", "server address or name to unicast to, or to relay to with -relay, the link multicast address if empty")
+	relay := fs.String("relay", "", "relay agent link address, act as a relay agent of that link instead of a client")
+	peer := fs.String("peer", "", "relay agent peer address, the link-local address of the interface if empty")
+	ifid := fs.String("interface-id", "", "relay agent interface-id, hex")
+	remoteID := fs.String("remote-id", "", "relay agent remote-id, hex")
+	remoteEN := fs.Uint("remote-en", 0, "enterprise number of the remote-id")
+	duid := fs.String("duid", "", "client DUID, hex, DUID-LL of the interface MAC if empty")
+	iaid := fs.String("iaid", "", "IAID, hex or decimal, the last 4 bytes of the interface MAC if empty")
+	serverID := fs.String("server-id", "", "server DUID, hex, taken from the Advertise by request")
+	oro := fs.String("oro", "", "comma separated option codes to request")
+	na := fs.Bool("na", true, "request an IA_NA")
+	pd := fs.Bool("pd", false, "request an IA_PD")
+	pdlen := fs.Int("pdlen", 0, "prefix length hint of the IA_PD")
+	addrs := fs.String("addr", "", "comma separated addresses held, or the address to leasequery")
+	prefixes := fs.String("prefix", "", "comma separated prefixes held")
+	rapid := fs.Bool("rapid", false, "ask for rapid commit on solicit and request")
+	reconf := fs.Bool("reconf-accept", false, "accept Reconfigure messages")
+	timeout := fs.Duration("timeout", DEFAULT_TIMEOUT, "time to wait for responses")
+
+	return func() *Probe {
+		p := Probe{
+			Iface:        *iface,
+			NA:           *na,
+			PD:           *pd,
+			PDLen:        *pdlen,
+			RapidCommit:  *rapid,
+			ReconfAccept: *reconf,
+			Timeout:      *timeout,
+		}
+		if *server != "" {
+			// a service name resolves through the cluster DNS
+			a, err := net.ResolveIPAddr("ip6", *server)
+			if err != nil {
+				fail("invalid server address %s: %v", *server, err)
+			}
+			p.Server = a.IP
+		}
+		if *relay != "" {
+			p.Relay = &Relay{LinkAddr: net.ParseIP(*relay), RemoteEN: uint32(*remoteEN)}
+			if p.Relay.LinkAddr == nil {
+				fail("invalid relay link address %s", *relay)
+			}
+			if *peer != "" {
+				if p.Relay.PeerAddr = net.ParseIP(*peer); p.Relay.PeerAddr == nil {
+					fail("invalid relay peer address %s", *peer)
+				}
+			}
+			var err error
+			if p.Relay.InterfaceID, err = hex.DecodeString(*ifid); err != nil {
+				fail("invalid interface-id %s: %v", *ifid, err)
+			}
+			if p.Relay.RemoteID, err = hex.DecodeString(*remoteID); err != nil {
+				fail("invalid remote-id %s: %v", *remoteID, err)
+			}
+		}
+
+		var mac net.HardwareAddr
+		if i, err := net.InterfaceByName(*iface); err == nil {
+			mac = i.HardwareAddr
+		}
+		if *duid != "" {
+			d, err := parseDuid(*duid)
+			if err != nil {
+				fail("invalid DUID %s: %v", *duid, err)
+			}
+			p.DUID = *d
+		} else if len(mac) > 0 {
+			p.DUID = dhcpv6.Duid{Type: dhcpv6.DUID_LL, HwType: iana.HWTypeEthernet, LinkLayerAddr: mac}
+		} else {
+			fail("no MAC on interface %s, -duid required", *iface)
+		}
+		if *iaid != "" {
+			if err := parseIaid(*iaid, &p.IAID); err != nil {
+				fail("invalid IAID %s: %v", *iaid, err)
+			}
+		} else if len(mac) >= 4 {
+			copy(p.IAID[:], mac[len(mac)-4:])
+		}
+		if *serverID != "" {
+			d, err := parseDuid(*serverID)
+			if err != nil {
+				fail("invalid server DUID %s: %v", *serverID, err)
+			}
+			p.ServerID = d
+		}
+		for _, s := range split(*oro) {
+			code, err := strconv.ParseUint(s, 10, 16)
+			if err != nil {
+				fail("invalid option code %s", s)
+			}
+			p.ORO = append(p.ORO, dhcpv6.OptionCode(code))
+		}
+		for _, s := range split(*addrs) {
+			a := net.ParseIP(s)
+			if a == nil || a.To4() != nil {
+				fail("invalid IPv6 address %s", s)
+			}
+			p.Addrs = append(p.Addrs, a)
+		}
+		for _, s := range split(*prefixes) {
+			_, pfx, err := net.ParseCIDR(s)
+			if err != nil {
+				fail("invalid prefix %s: %v", s, err)
+			}
+			p.Prefixes = append(p.Prefixes, pfx)
+		}
+		// held bindings are renewed as they are, no new IA is asked for
+		if len(p.Addrs) > 0 || len(p.Prefixes) > 0 {
+			p.NA, p.PD = false, false
+		}
+		return &p
+	}
+}
+
+// fail reports an invalid command line and exits
+func fail(format string, v ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", v...)
+	os.Exit(2)
+}
+
+func split(s string) []string {
+	list := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func parseDuid(s string) (*dhcpv6.Duid, error) {
+	b, err := hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+	if err != nil {
+		return nil, err
+	}
+	return dhcpv6.DuidFromBytes(b)
+}
+
+// parseIaid reads the IAID as 8 hex digits or a decimal number
+func parseIaid(s string, iaid *[4]byte) error {
+	if b, err := hex.DecodeString(s); err == nil && len(b) == 4 {
+		copy(iaid[:], b)
+		return nil
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint32(iaid[:], uint32(n))
+	return nil
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/iana"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/polarbroadband/rp1/proto/dhcp"
+)
+
+var (
+	DEFAULT_INTERVAL = 30 * time.Second
+	// number of recent probes /healtz looks at
+	DEFAULT_WINDOW = 3
+)
+
+// ProbeResult is the outcome of one monitoring probe
+type ProbeResult struct {
+	Time     time.Time     `json:"time"`
+	Success  bool          `json:"success"`
+	Reason   string        `json:"reason,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Addrs    []string      `json:"addrs,omitempty"`
+}
+
+// Monitor runs the full Solicit, Advertise, Request and Reply exchange of
+// the probe periodically and releases what it got, as a blackbox prober of
+// dhcp-svr
+type Monitor struct {
+	Host     string
+	Probe    *Probe
+	Interval time.Duration
+	Window   int
+
+	Probes   *prometheus.CounterVec
+	Failure  *prometheus.CounterVec
+	Duration *prometheus.HistogramVec
+	Success  prometheus.Gauge
+	Last     prometheus.Gauge
+
+	mu     sync.RWMutex
+	recent []ProbeResult
+}
+
+func NewMonitor(reg prometheus.Registerer, host string, p *Probe, interval time.Duration, window int) *Monitor {
+	m := &Monitor{
+		Host:     host,
+		Probe:    p,
+		Interval: interval,
+		Window:   window,
+		Probes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "dhcp_probes_total",
+				Help: "Number of DHCPv6 probes run, by result",
+			},
+			[]string{"result"},
+		),
+		Failure: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "dhcp_probe_failures_total",
+				Help: "Number of failed DHCPv6 probes, by reason",
+			},
+			[]string{"reason"},
+		),
+		Duration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "dhcp_probe_duration_seconds",
+				Help:    "Latency of the exchanges of the DHCPv6 probes, by result",
+				Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+			},
+			[]string{"exchange", "result"},
+		),
+		Success: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "dhcp_probe_success",
+				Help: "Whether the last DHCPv6 probe succeeded",
+			},
+		),
+		Last: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "dhcp_probe_last_success_timestamp_seconds",
+				Help: "Unix time of the last successful DHCPv6 probe",
+			},
+		),
+	}
+	reg.MustRegister(m.Probes)
+	reg.MustRegister(m.Failure)
+	reg.MustRegister(m.Duration)
+	reg.MustRegister(m.Success)
+	reg.MustRegister(m.Last)
+	return m
+}
+
+// Run probes every interval, never returns
+func (m *Monitor) Run() {
+	for {
+		r := m.Once()
+		if r.Success {
+			log.Printf("probe succeeded in %v, got %v", r.Duration, strings.Join(r.Addrs, " "))
+		} else {
+			log.Printf("probe failed, %s: %s", r.Reason, r.Error)
+		}
+		time.Sleep(m.Interval)
+	}
+}
+
+// Once runs a probe, records its result and returns it
+func (m *Monitor) Once() ProbeResult {
+	r := ProbeResult{Time: time.Now()}
+	reason, err := m.probe(&r)
+	r.Duration = time.Since(r.Time)
+	if err != nil {
+		r.Reason, r.Error = reason, err.Error()
+		m.Probes.WithLabelValues("failure").Inc()
+		m.Failure.WithLabelValues(reason).Inc()
+		m.Success.Set(0)
+	} else {
+		r.Success = true
+		m.Probes.WithLabelValues("success").Inc()
+		m.Success.Set(1)
+		m.Last.Set(float64(r.Time.Unix()))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recent = append(m.recent, r)
+	if len(m.recent) > m.Window {
+		m.recent = m.recent[len(m.recent)-m.Window:]
+	}
+	return r
+}
+
+// probe gets a lease and releases it, returns the failure reason and error.
+// The latency of both exchanges is observed whatever their outcome
+func (m *Monitor) probe(r *ProbeResult) (string, error) {
+	start := time.Now()
+	reply, addrs, prefixes, reason, err := m.request()
+	m.observe("request", start, err)
+	if err != nil {
+		return reason, err
+	}
+	for _, a := range addrs {
+		r.Addrs = append(r.Addrs, a.String())
+	}
+	for _, p := range prefixes {
+		r.Addrs = append(r.Addrs, p.String())
+	}
+
+	start = time.Now()
+	reason, err = m.release(reply, addrs, prefixes)
+	m.observe("release", start, err)
+	return reason, err
+}
+
+// request runs the full exchange, returns the Reply and what it leased
+func (m *Monitor) request() (*dhcpv6.Message, []net.IP, []*net.IPNet, string, error) {
+	conversation, err := m.Probe.Request()
+	if err != nil {
+		return nil, nil, nil, failureReason(conversation, err), err
+	}
+	reply := conversation[len(conversation)-1]
+	if status := replyStatus(reply); status != nil {
+		return nil, nil, nil, "status-" + dhcp.MetricLabel(status.StatusCode.String()), fmt.Errorf("%s: %s", status.StatusCode, status.StatusMessage)
+	}
+	addrs, prefixes := AdvertisedLeases(reply)
+	if len(addrs) == 0 && len(prefixes) == 0 {
+		return nil, nil, nil, "no-lease", errors.New("nothing leased")
+	}
+	return reply, addrs, prefixes, "", nil
+}
+
+// release gives back to the server of the Reply what it leased
+func (m *Monitor) release(reply *dhcpv6.Message, addrs []net.IP, prefixes []*net.IPNet) (string, error) {
+	rel := *m.Probe
+	rel.ServerID = reply.Options.ServerID()
+	rel.Addrs, rel.Prefixes = addrs, prefixes
+	rel.NA, rel.PD = false, false
+	msg, err := rel.NewMessage(dhcpv6.MessageTypeRelease)
+	if err != nil {
+		return "release", err
+	}
+	responses, err := rel.Exchange(msg)
+	if errors.Is(err, ErrNoResponse) {
+		return "release-no-reply", err
+	}
+	if err != nil {
+		return "release-error", err
+	}
+	if s := responses[0].Options.Status(); s != nil && s.StatusCode != iana.StatusSuccess {
+		return "release-status-" + dhcp.MetricLabel(s.StatusCode.String()), fmt.Errorf("%s: %s", s.StatusCode, s.StatusMessage)
+	}
+	return "", nil
+}
+
+// observe records the latency of an exchange since start by its outcome
+func (m *Monitor) observe(exchange string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.Duration.WithLabelValues(exchange, result).Observe(time.Since(start).Seconds())
+}
+
+// failureReason names where the exchange of the conversation stopped
+func failureReason(conversation []*dhcpv6.Message, err error) string {
+	if errors.Is(err, ErrNoResponse) {
+		switch len(conversation) {
+		case 0, 1:
+			return "no-advertise"
+		default:
+			return "no-reply"
+		}
+	}
+	if len(conversation) > 0 {
+		if s := replyStatus(conversation[len(conversation)-1]); s != nil {
+			return "status-" + dhcp.MetricLabel(s.StatusCode.String())
+		}
+	}
+	return "error"
+}
+
+// replyStatus returns the first failure status of the message or its IAs
+func replyStatus(msg *dhcpv6.Message) *dhcpv6.OptStatusCode {
+	statuses := []*dhcpv6.OptStatusCode{msg.Options.Status()}
+	for _, ia := range msg.Options.IANA() {
+		statuses = append(statuses, ia.Options.Status())
+	}
+	for _, ia := range msg.Options.IAPD() {
+		statuses = append(statuses, ia.Options.Status())
+	}
+	for _, s := range statuses {
+		if s != nil && s.StatusCode != iana.StatusSuccess {
+			return s
+		}
+	}
+	return nil
+}
+
+// Healtz is ready while any of the recent probes succeeded, and before the
+// first probe completes
+func (m *Monitor) Healtz(w http.ResponseWriter, r *http.Request) {
+	m.mu.RLock()
+	recent := append([]ProbeResult{}, m.recent...)
+	m.mu.RUnlock()
+	status := "starting"
+	if len(recent) > 0 {
+		status = "failing"
+		for _, p := range recent {
+			if p.Success {
+				status = "ready"
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if status == "failing" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"host": m.Host, "status": status, "probes": recent})
+}
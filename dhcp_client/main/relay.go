@@ -1,30 +1,64 @@
 package main
 
 import (
-	"log"
+	"fmt"
+	"net"
 
-	"github.com/insomniacslk/dhcp/dhcpv6/client6"
+	"github.com/insomniacslk/dhcp/dhcpv6"
 )
 
-func RelayFourMsgTransaction(client *client6.Client, intf string) {
-	// Exchange runs a Solicit-Advertise-Request-Reply transaction on the
-	// specified network interface, and returns a list of DHCPv6 packets
-	// (a "conversation") and an error if any. Notice that Exchange may
-	// return a non-empty packet list even if there is an error. This is
-	// intended, because the transaction may fail at any point, and we
-	// still want to know what packets were exchanged until then.
-	// A default Solicit packet will be used during the "conversation",
-	// which can be manipulated by using modifiers.
-	conversation, err := client.Exchange(intf)
+// Relay is a simulated relay agent forwarding the client messages from the
+// link of LinkAddr, as the access routers in front of dhcp-svr do
+type Relay struct {
+	LinkAddr    net.IP
+	PeerAddr    net.IP // client address, the link-local one of the interface if nil
+	InterfaceID []byte
+	RemoteID    []byte
+	RemoteEN    uint32 // enterprise number of the remote-id
+}
 
-	// Summary() prints a verbose representation of the exchanged packets.
-	for _, packet := range conversation {
-		log.Print(packet.Summary())
+// Forward wraps the client message into a Relay-forward
+func (r *Relay) Forward(msg *dhcpv6.Message, iface string) (*dhcpv6.RelayMessage, error) {
+	peer := r.PeerAddr
+	if peer == nil {
+		ll, err := dhcpv6.GetLinkLocalAddr(iface)
+		if err != nil {
+			return nil, fmt.Errorf("no peer address: %v", err)
+		}
+		peer = ll
 	}
-	// error handling is done *after* printing, so we still print the
-	// exchanged packets if any, as explained above.
+	forw, err := dhcpv6.EncapsulateRelay(msg, dhcpv6.MessageTypeRelayForward, r.LinkAddr, peer)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	if len(r.InterfaceID) > 0 {
+		forw.AddOption(dhcpv6.OptInterfaceID(r.InterfaceID))
+	}
+	if len(r.RemoteID) > 0 {
+		forw.AddOption(&dhcpv6.OptRemoteID{EnterpriseNumber: r.RemoteEN, RemoteID: r.RemoteID})
 	}
+	return forw, nil
+}
 
+// Decapsulate parses a server message, unwrapping the Relay-reply chain if
+// any
+func Decapsulate(data []byte) (*dhcpv6.Message, error) {
+	d, err := dhcpv6.FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	inner, err := dhcpv6.DecapsulateRelayIndex(d, -1)
+	if err != nil {
+		return nil, err
+	}
+	if inner.IsRelay() {
+		if inner, err = dhcpv6.DecapsulateRelay(inner); err != nil {
+			return nil, err
+		}
+	}
+	msg, ok := inner.(*dhcpv6.Message)
+	if !ok {
+		return nil, fmt.Errorf("unexpected %s", inner.Type())
+	}
+	return msg, nil
 }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+)
+
+// Summary is the JSON form of a DHCPv6 message
+type Summary struct {
+	Type     string            `json:"type"`
+	XID      string            `json:"xid"`
+	ClientID string            `json:"clientID,omitempty"`
+	ServerID string            `json:"serverID,omitempty"`
+	Status   *StatusSummary    `json:"status,omitempty"`
+	IAs      []IASummary       `json:"ias,omitempty"`
+	Options  map[string]string `json:"options,omitempty"`
+}
+
+type StatusSummary struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type IASummary struct {
+	Kind   string         `json:"kind"` // na or pd
+	IAID   string         `json:"iaid"`
+	T1     float64        `json:"t1"` // seconds
+	T2     float64        `json:"t2"`
+	Status *StatusSummary `json:"status,omitempty"`
+	Leases []LeaseSummary `json:"leases,omitempty"`
+}
+
+type LeaseSummary struct {
+	Addr      string         `json:"addr"` // address or prefix
+	Preferred float64        `json:"preferred"`
+	Valid     float64        `json:"valid"`
+	Status    *StatusSummary `json:"status,omitempty"`
+}
+
+// Conversation is the JSON form of an exchange
+type Conversation struct {
+	Messages []*Summary `json:"messages"`
+	Error    string     `json:"error,omitempty"`
+}
+
+func statusSummary(s *dhcpv6.OptStatusCode) *StatusSummary {
+	if s == nil {
+		return nil
+	}
+	return &StatusSummary{Code: s.StatusCode.String(), Message: s.StatusMessage}
+}
+
+// Summarize converts a message into its JSON form
+func Summarize(msg *dhcpv6.Message) *Summary {
+	s := Summary{
+		Type:    msg.Type().String(),
+		XID:     msg.TransactionID.String(),
+		Options: map[string]string{},
+	}
+	for _, opt := range msg.Options.Options {
+		switch o := opt.(type) {
+		case *dhcpv6.OptIANA:
+			ia := IASummary{Kind: "na", IAID: hex.EncodeToString(o.IaId[:]), T1: o.T1.Seconds(), T2: o.T2.Seconds(), Status: statusSummary(o.Options.Status())}
+			for _, a := range o.Options.Addresses() {
+				ia.Leases = append(ia.Leases, LeaseSummary{Addr: a.IPv6Addr.String(), Preferred: a.PreferredLifetime.Seconds(), Valid: a.ValidLifetime.Seconds(), Status: statusSummary(a.Options.Status())})
+			}
+			s.IAs = append(s.IAs, ia)
+		case *dhcpv6.OptIAPD:
+			ia := IASummary{Kind: "pd", IAID: hex.EncodeToString(o.IaId[:]), T1: o.T1.Seconds(), T2: o.T2.Seconds(), Status: statusSummary(o.Options.Status())}
+			for _, p := range o.Options.Prefixes() {
+				ia.Leases = append(ia.Leases, LeaseSummary{Addr: p.Prefix.String(), Preferred: p.PreferredLifetime.Seconds(), Valid: p.ValidLifetime.Seconds(), Status: statusSummary(p.Options.Status())})
+			}
+			s.IAs = append(s.IAs, ia)
+		case *dhcpv6.OptStatusCode:
+			s.Status = statusSummary(o)
+		default:
+			switch opt.Code() {
+			case dhcpv6.OptionClientID:
+				s.ClientID = hex.EncodeToString(opt.ToBytes())
+			case dhcpv6.OptionServerID:
+				s.ServerID = hex.EncodeToString(opt.ToBytes())
+			default:
+				s.Options[opt.Code().String()] = opt.String()
+			}
+		}
+	}
+	return &s
+}
+
+// Print writes the messages of an exchange and its error, as JSON or as the
+// message summaries
+func Print(w io.Writer, asJSON bool, conversation []*dhcpv6.Message, err error) {
+	if asJSON {
+		c := Conversation{Messages: []*Summary{}}
+		for _, msg := range conversation {
+			c.Messages = append(c.Messages, Summarize(msg))
+		}
+		if err != nil {
+			c.Error = err.Error()
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(c)
+		return
+	}
+	for _, msg := range conversation {
+		fmt.Fprintln(w, msg.Summary())
+	}
+	if err != nil {
+		fmt.Fprintf(w, "error: %v\n", err)
+	}
+}
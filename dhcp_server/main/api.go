@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/polarbroadband/rp1/proto/dhcp"
+)
+
+// ParseLeaseFilter reads a lease search out of the query parameters
+//
+//	addr          address, or network the addresses or prefixes are within
+//	duid, iaid    hex, colons allowed
+//	network       network name
+//	state         bound, offered or declined
+//	family        v4 or v6
+//	expireAfter   RFC 3339 time
+//	expireBefore  RFC 3339 time
+func ParseLeaseFilter(q url.Values) (LeaseFilter, error) {
+	f := LeaseFilter{
+		DUID:    strings.ToLower(strings.ReplaceAll(q.Get("duid"), ":", "")),
+		IAID:    strings.ToLower(strings.ReplaceAll(q.Get("iaid"), ":", "")),
+		Network: q.Get("network"),
+		State:   q.Get("state"),
+	}
+	if a := q.Get("addr"); a != "" {
+		nets, err := ParseNets(a)
+		if err != nil || len(nets) != 1 {
+			return f, fmt.Errorf("invalid addr %s", a)
+		}
+		f.Addr = nets[0]
+	}
+	switch q.Get("family") {
+	case "":
+	case "v4":
+		f.IPv4 = true
+	case "v6":
+		f.IPv6 = true
+	default:
+		return f, fmt.Errorf("invalid family %s", q.Get("family"))
+	}
+	for param, t := range map[string]*time.Time{"expireAfter": &f.ExpireAfter, "expireBefore": &f.ExpireBefore} {
+		if v := q.Get(param); v != "" {
+			ts, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, fmt.Errorf("invalid %s %v", param, err)
+			}
+			*t = ts
+		}
+	}
+	return f, nil
+}
+
+// LeaseHandler lists the unexpired bindings matching the query parameters,
+// see ParseLeaseFilter
+func (db *LeaseDB) LeaseHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	f, err := ParseLeaseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	leases := []*dhcp.Lease{}
+	for _, b := range db.Search(f) {
+		l := b.ToLease()
+		// a secret between the server and the client
+		l.ReconfigureKey = ""
+		leases = append(leases, l)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(leases); err != nil {
+		db.Log.Errorf("unable to send leases to %s: %v", r.RemoteAddr, err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	uuid "github.com/google/UUID"
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/iana"
+	"github.com/polarbroadband/rp1/etcdlib"
+	etcd "go.etcd.io/etcd/client/v3"
+)
+
+var (
+	// etcd key of the DUID generated when none is configured, shared by every
+	// server replica as the bindings are
+	DUID_KEY = "duid"
+	// RFC 8415 section 11.2 DUID-LLT time base
+	DUID_EPOCH = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// ParseDuid reads a DUID of the given type, a UUID for DUID-UUID, or the hex
+// encoded DUID of any type
+func ParseDuid(duidType, s string) (*dhcpv6.Duid, error) {
+	if strings.ToUpper(duidType) == "UUID" {
+		if id, err := uuid.Parse(s); err == nil {
+			b, _ := id.MarshalBinary()
+			return &dhcpv6.Duid{Type: dhcpv6.DUID_UUID, Uuid: b}, nil
+		}
+	}
+	data, err := hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DUID %s: %v", s, err)
+	}
+	d, err := dhcpv6.DuidFromBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DUID %s: %v", s, err)
+	}
+	return d, nil
+}
+
+// NewDuid generates a DUID of the given type, DUID-LLT takes the hardware
+// address of the interface, DUID-EN the enterprise number and a random
+// identifier
+func NewDuid(duidType string, iface string, enterprise uint32) (*dhcpv6.Duid, error) {
+	switch strings.ToUpper(duidType) {
+	case "UUID", "":
+		b, _ := uuid.New().MarshalBinary()
+		return &dhcpv6.Duid{Type: dhcpv6.DUID_UUID, Uuid: b}, nil
+	case "LLT":
+		i, err := net.InterfaceByName(iface)
+		if err != nil {
+			return nil, err
+		}
+		if len(i.HardwareAddr) == 0 {
+			return nil, fmt.Errorf("interface %s without hardware address", iface)
+		}
+		return &dhcpv6.Duid{
+			Type:          dhcpv6.DUID_LLT,
+			HwType:        iana.HWTypeEthernet,
+			Time:          uint32(time.Since(DUID_EPOCH) / time.Second),
+			LinkLayerAddr: i.HardwareAddr,
+		}, nil
+	case "EN":
+		if enterprise == 0 {
+			return nil, fmt.Errorf("DUID-EN requires an enterprise number")
+		}
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return nil, err
+		}
+		return &dhcpv6.Duid{Type: dhcpv6.DUID_EN, EnterpriseNumber: enterprise, EnterpriseIdentifier: id}, nil
+	}
+	return nil, fmt.Errorf("unsupported DUID type %s", duidType)
+}
+
+// ServerDuid returns the configured DUID, else the one saved in etcd, else a
+// new one saved in etcd unless another replica saved one first
+func ServerDuid(depot *etcdlib.KvDepot, duidType, configured, iface string, enterprise uint32) (*dhcpv6.Duid, error) {
+	if configured != "" {
+		return ParseDuid(duidType, configured)
+	}
+	saved, err := depot.Get(DUID_KEY)
+	if err != nil {
+		return nil, err
+	}
+	if saved != nil {
+		return ParseDuid("", string(saved.Value))
+	}
+	d, err := NewDuid(duidType, iface, enterprise)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), depot.OprTimeout)
+	defer cancel()
+	key := depot.Depot + "/" + DUID_KEY
+	resp, err := depot.Conn.Txn(ctx).
+		If(etcd.Compare(etcd.CreateRevision(key), "=", 0)).
+		Then(etcd.OpPut(key, hex.EncodeToString(d.ToBytes()))).
+		Else(etcd.OpGet(key)).
+		Commit()
+	if err != nil {
+		return nil, fmt.Errorf("unable to save DUID %v", err)
+	}
+	if !resp.Succeeded {
+		return ParseDuid("", string(resp.Responses[0].GetResponseRange().Kvs[0].Value))
+	}
+	depot.Log.Infof("generated %s %x", d.Type, d.ToBytes())
+	return d, nil
+}
+
+// EnvPort reads a port number from the environment, 0 disables the listener
+func EnvPort(name string, def int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	port, err := strconv.Atoi(v)
+	if err != nil || port < 0 || port > 0xffff {
+		return 0, fmt.Errorf("invalid env variable %s: %s", name, v)
+	}
+	return port, nil
+}
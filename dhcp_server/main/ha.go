@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/polarbroadband/rp1/etcdlib"
+	"github.com/sirupsen/logrus"
+	etcd "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+var (
+	ELECTION_KEY = "leader"
+	// seconds a leader keeps the role after losing etcd
+	DEFAULT_ELECTION_TTL = 5
+
+	ErrNotLeader = errors.New("not the leader")
+)
+
+// Election elects the replica serving clients among the ones sharing the
+// bindings, the others stand by with the bindings mirrored and take over
+// once the session of the leader expires
+type Election struct {
+	Depot *etcdlib.KvDepot
+	ID    string
+	TTL   int
+	Log   *logrus.Entry
+
+	mu     sync.RWMutex
+	leader *concurrency.Election // set while leading
+}
+
+func NewElection(depot *etcdlib.KvDepot, id string, ttl int) *Election {
+	return &Election{
+		Depot: depot,
+		ID:    id,
+		TTL:   ttl,
+		Log:   depot.Log.WithField("election", depot.Depot+"/"+ELECTION_KEY),
+	}
+}
+
+// Run campaigns for leadership until the context is done
+func (e *Election) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := e.campaign(ctx); err != nil && ctx.Err() == nil {
+			e.Log.Errorf("leader election failed: %v", err)
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+func (e *Election) campaign(ctx context.Context) error {
+	s, err := concurrency.NewSession(e.Depot.Conn, concurrency.WithTTL(e.TTL), concurrency.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	// campaigning stops together with the session
+	cctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.Done():
+			cancel()
+		case <-cctx.Done():
+		}
+	}()
+
+	el := concurrency.NewElection(s, e.Depot.Depot+"/"+ELECTION_KEY)
+	e.Log.Info("standby, campaign for leader")
+	if err := el.Campaign(cctx, e.ID); err != nil {
+		return err
+	}
+	e.set(el)
+	e.Log.Info("elected leader")
+	<-cctx.Done()
+	e.set(nil)
+	e.Log.Warn("leadership lost")
+	return nil
+}
+
+func (e *Election) set(el *concurrency.Election) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.leader = el
+}
+
+// Leading reports whether the replica is the leader
+func (e *Election) Leading() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader != nil
+}
+
+// Resign hands leadership over to a standby replica
+func (e *Election) Resign() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.leader == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.Depot.OprTimeout)
+	defer cancel()
+	if err := e.leader.Resign(ctx); err != nil {
+		e.Log.Errorf("unable to resign: %v", err)
+	} else {
+		e.Log.Info("resigned")
+	}
+	e.leader = nil
+}
+
+// Txn commits the operations only while the replica is still the leader in
+// etcd, a former leader not yet aware of its session expiry changes nothing
+func (e *Election) Txn(ops ...etcd.Op) error {
+	e.mu.RLock()
+	el := e.leader
+	e.mu.RUnlock()
+	if el == nil {
+		return ErrNotLeader
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.Depot.OprTimeout)
+	defer cancel()
+	resp, err := e.Depot.Conn.Txn(ctx).
+		If(etcd.Compare(etcd.CreateRevision(el.Key()), "=", el.Rev())).
+		Then(ops...).
+		Commit()
+	if err != nil {
+		return fmt.Errorf("unable to commit to etcd %v", err)
+	}
+	if !resp.Succeeded {
+		return ErrNotLeader
+	}
+	return nil
+}
+
+// Put saves a key of the depot as Txn does, expiring with the etcd lease
+func (e *Election) Put(key, val string, lease int64) error {
+	return e.Txn(etcd.OpPut(e.Depot.Depot+"/"+key, val, etcd.WithLease(etcd.LeaseID(lease))))
+}
+
+// Delete removes a key of the depot as Txn does
+func (e *Election) Delete(key string) error {
+	return e.Txn(etcd.OpDelete(e.Depot.Depot + "/" + key))
+}
@@ -0,0 +1,504 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/iana"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"github.com/polarbroadband/rp1/proto/dhcp"
+)
+
+type DhcpServer struct {
+	DUID     dhcpv6.Duid
+	ServerIP net.IP // DHCPv4 server identifier
+	Leases   *LeaseDB
+	*Metrics
+	Log *logrus.Entry
+	// requestors allowed to send LEASEQUERY, nobody if empty
+	LeaseQueryFrom []*net.IPNet
+}
+
+// Standby reports whether another replica serves the clients
+func (s *DhcpServer) Standby() bool {
+	return s.Leases.Election != nil && !s.Leases.Election.Leading()
+}
+
+func (s *DhcpServer) Handler(conn net.PacketConn, peer net.Addr, r dhcpv6.DHCPv6) {
+	log := s.Log.WithFields(logrus.Fields{"family": "v6", "peer": peer.String()})
+	defer func() {
+		// a message must never take the server down
+		if err := recover(); err != nil {
+			log.Errorf("message handling failed: %v", err)
+			s.Response.With(prometheus.Labels{"family": "v6", "type": "error"}).Inc()
+		}
+	}()
+	log.Trace(r.Summary())
+	t, err := NewTxn(r, log)
+	if err != nil {
+		log.Warnf("invalid message: %v", err)
+		s.Request.With(prometheus.Labels{"family": "v6", "type": "malformed"}).Inc()
+		return
+	}
+	s.Request.With(prometheus.Labels{"family": "v6", "type": dhcp.MetricLabel(t.Msg.Type().String())}).Inc()
+	if udp, ok := peer.(*net.UDPAddr); ok {
+		t.Peer = udp.IP
+	}
+	if s.Standby() {
+		t.Log.Debug("standby, discarded")
+		s.Response.With(prometheus.Labels{"family": "v6", "type": "standby"}).Inc()
+		return
+	}
+	t.Scope = s.Leases.SelectScope(false, t.Relayed(), t.LinkAddr, t.InterfaceID)
+	if t.Scope == nil {
+		t.Log.WithField("interfaceID", fmt.Sprintf("%x", t.InterfaceID)).Warn("no network for client")
+	} else {
+		t.Log = t.Log.WithField("network", t.Scope.Name)
+	}
+
+	resp, err := s.Reply(t)
+	if err != nil {
+		t.Log.Errorf("unable to build response: %v", err)
+		s.Response.With(prometheus.Labels{"family": "v6", "type": "error"}).Inc()
+		return
+	}
+	if resp == nil {
+		t.Log.Info("discarded")
+		s.Response.With(prometheus.Labels{"family": "v6", "type": "discarded"}).Inc()
+		return
+	}
+	t.Log.Trace(resp.Summary())
+	s.Response.With(prometheus.Labels{"family": "v6", "type": dhcp.MetricLabel(resp.Type().String())}).Inc()
+	s.countFailures(resp)
+
+	// relayed replies go back to the outermost relay agent
+	out := RelayReply(t.Relays, resp)
+	if _, err := conn.WriteTo(out.ToBytes(), peer); err != nil {
+		t.Log.Errorf("failed to send %s: %v", resp.Type(), err)
+	} else {
+		t.Log.Infof("%s sent", resp.Type())
+	}
+}
+
+// Reply builds the response to a client message as RFC 8415 section 18.3
+// defines it, a nil response means the message is discarded
+func (s *DhcpServer) Reply(t *Txn) (*dhcpv6.Message, error) {
+	msg, scope := t.Msg, t.Scope
+	sid := msg.Options.ServerID()
+	switch msg.Type() {
+	case dhcpv6.MessageTypeSolicit, dhcpv6.MessageTypeConfirm, dhcpv6.MessageTypeRebind:
+		// must not carry a server identifier
+		if sid != nil {
+			return nil, nil
+		}
+	case dhcpv6.MessageTypeRequest, dhcpv6.MessageTypeRenew, dhcpv6.MessageTypeRelease, dhcpv6.MessageTypeDecline:
+		// must be addressed to this server
+		if sid == nil || !sid.Equal(s.DUID) {
+			return nil, nil
+		}
+	case dhcpv6.MessageTypeInformationRequest:
+		if sid != nil && !sid.Equal(s.DUID) {
+			return nil, nil
+		}
+	case dhcpv6.MessageTypeLeaseQuery:
+		return s.LeaseQuery(t)
+	default:
+		return nil, nil
+	}
+	if msg.Options.ClientID() == nil && msg.Type() != dhcpv6.MessageTypeInformationRequest {
+		return nil, nil
+	}
+
+	switch msg.Type() {
+
+	// SOLICIT
+	case dhcpv6.MessageTypeSolicit:
+		// two message exchange when both the client and the network want it
+		if msg.GetOneOption(dhcpv6.OptionRapidCommit) != nil && scope != nil && scope.Network.GetRapidCommit() {
+			reply, err := dhcpv6.NewReplyFromMessage(msg, dhcpv6.WithServerID(s.DUID))
+			if err != nil {
+				return nil, err
+			}
+			s.assignIANA(t, reply, true)
+			s.assignIAPD(t, reply, true)
+			s.acceptReconfigure(t, reply)
+			AddRequestedOptions(msg, reply, t.Options())
+			return reply, nil
+		}
+		adv, err := dhcpv6.NewAdvertiseFromSolicit(msg, dhcpv6.WithServerID(s.DUID))
+		if err != nil {
+			return nil, err
+		}
+		s.assignIANA(t, adv, false)
+		s.assignIAPD(t, adv, false)
+		AddRequestedOptions(msg, adv, t.Options())
+		return adv, nil
+
+	// REQUEST
+	case dhcpv6.MessageTypeRequest:
+		reply, err := dhcpv6.NewReplyFromMessage(msg, dhcpv6.WithServerID(s.DUID))
+		if err != nil {
+			return nil, err
+		}
+		s.assignIANA(t, reply, true)
+		s.assignIAPD(t, reply, true)
+		s.acceptReconfigure(t, reply)
+		AddRequestedOptions(msg, reply, t.Options())
+		return reply, nil
+
+	// RENEW, REBIND
+	case dhcpv6.MessageTypeRenew, dhcpv6.MessageTypeRebind:
+		reply, err := dhcpv6.NewReplyFromMessage(msg, dhcpv6.WithServerID(s.DUID))
+		if err != nil {
+			return nil, err
+		}
+		s.renewIANA(t, reply)
+		s.renewIAPD(t, reply)
+		s.acceptReconfigure(t, reply)
+		AddRequestedOptions(msg, reply, t.Options())
+		return reply, nil
+
+	// RELEASE, DECLINE
+	case dhcpv6.MessageTypeRelease, dhcpv6.MessageTypeDecline:
+		// built by hand, the library makes no Reply out of a Decline
+		reply := &dhcpv6.Message{
+			MessageType:   dhcpv6.MessageTypeReply,
+			TransactionID: msg.TransactionID,
+		}
+		reply.AddOption(msg.GetOneOption(dhcpv6.OptionClientID))
+		reply.AddOption(dhcpv6.OptServerID(s.DUID))
+		s.releaseIA(t, reply)
+		return reply, nil
+
+	// CONFIRM
+	case dhcpv6.MessageTypeConfirm:
+		addrs := []net.IP{}
+		for _, ia := range msg.Options.IANA() {
+			for _, a := range ia.Options.Addresses() {
+				addrs = append(addrs, a.IPv6Addr)
+			}
+		}
+		// nothing to confirm or unknown link, the client keeps waiting
+		if len(addrs) == 0 || scope == nil {
+			return nil, nil
+		}
+		status := &dhcpv6.OptStatusCode{StatusCode: iana.StatusSuccess, StatusMessage: "all addresses on link"}
+		for _, a := range addrs {
+			if !scope.OnLink(a) {
+				status = &dhcpv6.OptStatusCode{StatusCode: iana.StatusNotOnLink, StatusMessage: a.String() + " not on link"}
+				break
+			}
+		}
+		return dhcpv6.NewReplyFromMessage(msg, dhcpv6.WithServerID(s.DUID), dhcpv6.WithOption(status))
+
+	// INFORMATION-REQUEST
+	case dhcpv6.MessageTypeInformationRequest:
+		reply := &dhcpv6.Message{
+			MessageType:   dhcpv6.MessageTypeReply,
+			TransactionID: msg.TransactionID,
+		}
+		if cid := msg.GetOneOption(dhcpv6.OptionClientID); cid != nil {
+			reply.AddOption(cid)
+		}
+		reply.AddOption(dhcpv6.OptServerID(s.DUID))
+		AddRequestedOptions(msg, reply, t.Options())
+		return reply, nil
+	}
+	return nil, nil
+}
+
+// assignIANA answers every IA_NA of the client message with an address from
+// the scope, the reserved one if any, offered on Solicit and committed on
+// Request
+func (s *DhcpServer) assignIANA(t *Txn, resp *dhcpv6.Message, commit bool) {
+	msg, scope, duid := t.Msg, t.Scope, t.DUID
+	fixed := t.ReservedAddr()
+	for i, ia := range msg.Options.IANA() {
+		iaid := IaidString(ia.IaId)
+		log := t.Log.WithField("iana", iaid)
+		// the reservation goes to the first IA only
+		if i > 0 {
+			fixed = nil
+		}
+		var hint net.IP
+		if fixed != nil {
+			hint = fixed
+		} else if a := ia.Options.OneAddress(); a != nil {
+			hint = a.IPv6Addr
+			if err := s.Leases.CheckConflict(hint, duid, iaid); err != nil {
+				s.Conflict.With(prometheus.Labels{"family": "v6"}).Inc()
+				log.WithField("hint", hint).Warnf("requested address %v", err)
+			}
+		}
+
+		var (
+			b   *Binding
+			err error
+		)
+		if scope == nil {
+			err = ErrNoAddrsAvail
+		} else if commit {
+			b, err = s.Leases.Commit(scope, duid, iaid, hint, 0, fixed != nil)
+		} else {
+			b, err = s.Leases.Offer(scope, duid, iaid, hint, 0, fixed != nil)
+		}
+		if err != nil {
+			log.Warn(err)
+			if err == ErrConflict {
+				s.Conflict.With(prometheus.Labels{"family": "v6"}).Inc()
+			}
+			code := iana.StatusNoAddrsAvail
+			if err != ErrNoAddrsAvail {
+				code = iana.StatusUnspecFail
+			}
+			resp.AddOption(iaStatus(ia.IaId, code, err.Error()))
+			continue
+		}
+		log.WithFields(BindingFields(b)).Info(b.State.String())
+		resp.AddOption(iaBinding(ia.IaId, b))
+	}
+}
+
+// renewIANA extends the bindings of the IA_NAs of a Renew or Rebind, the
+// addresses no longer appropriate for the link are returned with zero lifetimes
+func (s *DhcpServer) renewIANA(t *Txn, resp *dhcpv6.Message) {
+	msg, scope, duid := t.Msg, t.Scope, t.DUID
+	for _, ia := range msg.Options.IANA() {
+		iaid := IaidString(ia.IaId)
+		log := t.Log.WithField("iana", iaid)
+		b, err := s.Leases.Renew(scope, ClientKey(duid, iaid))
+		switch err {
+		case nil:
+			log.WithFields(BindingFields(b)).Info("renewed")
+			opt := iaBinding(ia.IaId, b)
+			for _, a := range ia.Options.Addresses() {
+				if !a.IPv6Addr.Equal(b.Addr) {
+					opt.Options.Add(&dhcpv6.OptIAAddress{IPv6Addr: a.IPv6Addr})
+				}
+			}
+			resp.AddOption(opt)
+		case ErrNotOnLink:
+			log.WithFields(BindingFields(b)).Warn("binding not on link")
+			opt := &dhcpv6.OptIANA{IaId: ia.IaId}
+			for _, a := range ia.Options.Addresses() {
+				opt.Options.Add(&dhcpv6.OptIAAddress{IPv6Addr: a.IPv6Addr})
+			}
+			resp.AddOption(opt)
+		case ErrNoBinding:
+			log.Warn(err)
+			resp.AddOption(iaStatus(ia.IaId, iana.StatusNoBinding, err.Error()))
+		default:
+			log.Warn(err)
+			resp.AddOption(iaStatus(ia.IaId, iana.StatusUnspecFail, err.Error()))
+		}
+	}
+}
+
+// releaseIA removes the bindings of the IA_NAs and IA_PDs of a Release or the
+// IA_NAs of a Decline. Every IA is answered with its status, NoBinding when
+// it has no binding or, on Decline, when any of its addresses is not declined
+func (s *DhcpServer) releaseIA(t *Txn, resp *dhcpv6.Message) {
+	msg, duid := t.Msg, t.DUID
+	for _, ia := range msg.Options.IANA() {
+		iaid := IaidString(ia.IaId)
+		log := t.Log.WithField("iana", iaid)
+		code, text := iana.StatusSuccess, "released"
+		if msg.Type() == dhcpv6.MessageTypeDecline {
+			text = "declined"
+			failed := []string{}
+			for _, a := range ia.Options.Addresses() {
+				if b, err := s.Leases.Decline(ClientKey(duid, iaid), a.IPv6Addr); err != nil {
+					log.Warn(err)
+					failed = append(failed, fmt.Sprintf("%s %v", a.IPv6Addr, err))
+				} else {
+					log.WithFields(BindingFields(b)).Info("declined")
+					s.Decline.With(prometheus.Labels{"family": "v6"}).Inc()
+				}
+			}
+			if len(ia.Options.Addresses()) == 0 {
+				code, text = iana.StatusNoBinding, "no address to decline"
+			} else if len(failed) > 0 {
+				code, text = iana.StatusNoBinding, strings.Join(failed, ", ")
+			}
+		} else if b, err := s.Leases.Release(ClientKey(duid, iaid)); err != nil {
+			log.Warn(err)
+			code, text = iana.StatusNoBinding, err.Error()
+		} else {
+			log.WithFields(BindingFields(b)).Info("released")
+		}
+		resp.AddOption(iaStatus(ia.IaId, code, text))
+	}
+	// prefixes are released but never declined
+	if msg.Type() == dhcpv6.MessageTypeRelease {
+		for _, ia := range msg.Options.IAPD() {
+			iaid := IaidString(ia.IaId)
+			log := t.Log.WithField("iapd", iaid)
+			if b, err := s.Leases.Release(PrefixKey(duid, iaid)); err != nil {
+				log.Warn(err)
+				resp.AddOption(pdStatus(ia.IaId, iana.StatusNoBinding, err.Error()))
+			} else {
+				log.WithFields(BindingFields(b)).Info("released")
+				resp.AddOption(pdStatus(ia.IaId, iana.StatusSuccess, "released"))
+			}
+		}
+	}
+	resp.AddOption(&dhcpv6.OptStatusCode{StatusCode: iana.StatusSuccess, StatusMessage: msg.Type().String() + " received"})
+}
+
+// assignIAPD answers every IA_PD of the client message with a prefix
+// delegated out of the scope, the reserved one if any, offered on Solicit and
+// committed on Request
+func (s *DhcpServer) assignIAPD(t *Txn, resp *dhcpv6.Message, commit bool) {
+	msg, scope, duid := t.Msg, t.Scope, t.DUID
+	fixed := t.ReservedPrefix()
+	for i, ia := range msg.Options.IAPD() {
+		iaid := IaidString(ia.IaId)
+		log := t.Log.WithField("iapd", iaid)
+		// the reservation goes to the first IA only
+		if i > 0 {
+			fixed = nil
+		}
+		var hint net.IP
+		plen := 0
+		if fixed != nil {
+			hint = fixed.IP
+			plen, _ = fixed.Mask.Size()
+		} else {
+			if scope != nil {
+				plen = scope.Delegated
+			}
+			for _, p := range ia.Options.Prefixes() {
+				if p.Prefix != nil && !p.Prefix.IP.IsUnspecified() {
+					hint = p.Prefix.IP
+					break
+				}
+			}
+		}
+
+		var (
+			b   *Binding
+			err error
+		)
+		if scope == nil || plen == 0 {
+			err = ErrNoPrefixAvail
+		} else if commit {
+			b, err = s.Leases.Commit(scope, duid, iaid, hint, plen, fixed != nil)
+		} else {
+			b, err = s.Leases.Offer(scope, duid, iaid, hint, plen, fixed != nil)
+		}
+		if err != nil {
+			log.Warn(err)
+			if err == ErrConflict {
+				s.Conflict.With(prometheus.Labels{"family": "v6"}).Inc()
+			}
+			code := iana.StatusNoPrefixAvail
+			if err != ErrNoPrefixAvail {
+				code = iana.StatusUnspecFail
+			}
+			resp.AddOption(pdStatus(ia.IaId, code, err.Error()))
+			continue
+		}
+		log.WithFields(BindingFields(b)).Info(b.State.String())
+		resp.AddOption(pdBinding(ia.IaId, b))
+	}
+}
+
+// renewIAPD extends the delegated prefixes of the IA_PDs of a Renew or
+// Rebind, the prefixes no longer appropriate are returned with zero lifetimes
+func (s *DhcpServer) renewIAPD(t *Txn, resp *dhcpv6.Message) {
+	msg, scope, duid := t.Msg, t.Scope, t.DUID
+	for _, ia := range msg.Options.IAPD() {
+		iaid := IaidString(ia.IaId)
+		log := t.Log.WithField("iapd", iaid)
+		b, err := s.Leases.Renew(scope, PrefixKey(duid, iaid))
+		switch err {
+		case nil:
+			log.WithFields(BindingFields(b)).Info("renewed")
+			opt := pdBinding(ia.IaId, b)
+			for _, p := range ia.Options.Prefixes() {
+				if p.Prefix != nil && p.Prefix.String() != b.Prefix().String() {
+					opt.Options.Add(&dhcpv6.OptIAPrefix{Prefix: p.Prefix})
+				}
+			}
+			resp.AddOption(opt)
+		case ErrNotOnLink:
+			log.WithFields(BindingFields(b)).Warn("binding not appropriate")
+			opt := &dhcpv6.OptIAPD{IaId: ia.IaId}
+			for _, p := range ia.Options.Prefixes() {
+				opt.Options.Add(&dhcpv6.OptIAPrefix{Prefix: p.Prefix})
+			}
+			resp.AddOption(opt)
+		case ErrNoBinding:
+			log.Warn(err)
+			resp.AddOption(pdStatus(ia.IaId, iana.StatusNoBinding, err.Error()))
+		default:
+			log.Warn(err)
+			resp.AddOption(pdStatus(ia.IaId, iana.StatusUnspecFail, err.Error()))
+		}
+	}
+}
+
+// countFailures counts the IAs of the response refused with a status code
+func (s *DhcpServer) countFailures(resp *dhcpv6.Message) {
+	statuses := []*dhcpv6.OptStatusCode{}
+	for _, ia := range resp.Options.IANA() {
+		statuses = append(statuses, ia.Options.Status())
+	}
+	for _, ia := range resp.Options.IAPD() {
+		statuses = append(statuses, ia.Options.Status())
+	}
+	for _, st := range statuses {
+		if st != nil && st.StatusCode != iana.StatusSuccess {
+			s.Failure.With(prometheus.Labels{"family": "v6", "status": dhcp.MetricLabel(st.StatusCode.String())}).Inc()
+		}
+	}
+}
+
+// BindingFields returns the log fields of a binding
+func BindingFields(b *Binding) logrus.Fields {
+	return logrus.Fields{"addr": AddrKey(b.Addr, b.PrefixLen), "network": b.Network, "expire": b.Expire}
+}
+
+func iaStatus(iaid [4]byte, code iana.StatusCode, text string) *dhcpv6.OptIANA {
+	return &dhcpv6.OptIANA{
+		IaId: iaid,
+		Options: dhcpv6.IdentityOptions{Options: dhcpv6.Options{
+			&dhcpv6.OptStatusCode{StatusCode: code, StatusMessage: text},
+		}},
+	}
+}
+
+func iaBinding(iaid [4]byte, b *Binding) *dhcpv6.OptIANA {
+	return &dhcpv6.OptIANA{
+		IaId: iaid,
+		T1:   b.Preferred / 2,
+		T2:   b.Preferred * 4 / 5,
+		Options: dhcpv6.IdentityOptions{Options: dhcpv6.Options{
+			&dhcpv6.OptIAAddress{IPv6Addr: b.Addr, PreferredLifetime: b.Preferred, ValidLifetime: b.Valid},
+		}},
+	}
+}
+
+func pdStatus(iaid [4]byte, code iana.StatusCode, text string) *dhcpv6.OptIAPD {
+	return &dhcpv6.OptIAPD{
+		IaId: iaid,
+		Options: dhcpv6.PDOptions{Options: dhcpv6.Options{
+			&dhcpv6.OptStatusCode{StatusCode: code, StatusMessage: text},
+		}},
+	}
+}
+
+func pdBinding(iaid [4]byte, b *Binding) *dhcpv6.OptIAPD {
+	return &dhcpv6.OptIAPD{
+		IaId: iaid,
+		T1:   b.Preferred / 2,
+		T2:   b.Preferred * 4 / 5,
+		Options: dhcpv6.PDOptions{Options: dhcpv6.Options{
+			&dhcpv6.OptIAPrefix{Prefix: b.Prefix(), PreferredLifetime: b.Preferred, ValidLifetime: b.Valid},
+		}},
+	}
+}
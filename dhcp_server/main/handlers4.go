@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/iana"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"github.com/polarbroadband/rp1/proto/dhcp"
+)
+
+var (
+	// IAID of DHCPv4 bindings, apart from the ones of the DHCPv6 IAs
+	V4_IAID = "v4"
+)
+
+// Txn4 is a DHCPv4 client message being served, the client identifier or
+// else the hardware address is the identity of its binding
+type Txn4 struct {
+	Client
+	Msg      *dhcpv4.DHCPv4
+	ID       string
+	LinkAddr net.IP
+}
+
+// NewTxn4 identifies the client of a message, the log entry of the
+// transaction carries its ID, the client identity and the relay address
+func NewTxn4(m *dhcpv4.DHCPv4, log *logrus.Entry) *Txn4 {
+	t := Txn4{
+		Client:   Client{MAC: m.ClientHWAddr},
+		Msg:      m,
+		LinkAddr: m.GatewayIPAddr,
+	}
+	if cid := m.GetOneOption(dhcpv4.OptionClientIdentifier); len(cid) > 0 {
+		t.ID = hex.EncodeToString(cid)
+		// RFC 4361 client identifier, type 255 followed by IAID and DUID
+		if cid[0] == 0xff && len(cid) > 5 {
+			t.DUID = hex.EncodeToString(cid[5:])
+		}
+	} else {
+		t.ID = hex.EncodeToString(append([]byte{byte(m.HWType)}, m.ClientHWAddr...))
+	}
+	if rai := m.RelayAgentInfo(); rai != nil {
+		t.InterfaceID = rai.Get(dhcpv4.AgentCircuitIDSubOption)
+		t.RemoteID = rai.Get(dhcpv4.AgentRemoteIDSubOption)
+		// RFC 3527 link selection overrides the relay address
+		if ls := rai.Get(dhcpv4.LinkSelectionSubOption); len(ls) == net.IPv4len {
+			t.LinkAddr = net.IP(ls)
+		}
+	}
+	t.Log = log.WithFields(logrus.Fields{
+		"xid":      m.TransactionID.String(),
+		"type":     m.MessageType().String(),
+		"clientID": t.ID,
+	})
+	if t.Relayed() {
+		t.Log = t.Log.WithField("link", t.LinkAddr.String())
+	}
+	return &t
+}
+
+// Relayed reports whether the client message came through a relay agent
+func (t *Txn4) Relayed() bool {
+	return t.Msg.GatewayIPAddr != nil && !t.Msg.GatewayIPAddr.IsUnspecified()
+}
+
+// ReplyAddr is where the response goes as RFC 2131 section 4.1 defines it:
+// the relay agent, else the address the client is bound to, else a broadcast
+// since the client has no usable address, as for any Nak
+func (t *Txn4) ReplyAddr(resp *dhcpv4.DHCPv4) *net.UDPAddr {
+	if t.Relayed() {
+		return &net.UDPAddr{IP: t.Msg.GatewayIPAddr, Port: dhcpv4.ServerPort}
+	}
+	if ciaddr := t.Msg.ClientIPAddr; ciaddr != nil && !ciaddr.IsUnspecified() && resp.MessageType() != dhcpv4.MessageTypeNak {
+		return &net.UDPAddr{IP: ciaddr, Port: dhcpv4.ClientPort}
+	}
+	return &net.UDPAddr{IP: net.IPv4bcast, Port: dhcpv4.ClientPort}
+}
+
+func (s *DhcpServer) Handler4(conn net.PacketConn, peer net.Addr, req *dhcpv4.DHCPv4) {
+	log := s.Log.WithFields(logrus.Fields{"family": "v4", "peer": peer.String()})
+	defer func() {
+		// a message must never take the server down
+		if err := recover(); err != nil {
+			log.Errorf("message handling failed: %v", err)
+			s.Response.With(prometheus.Labels{"family": "v4", "type": "error"}).Inc()
+		}
+	}()
+	log.Trace(req.Summary())
+	if req.OpCode != dhcpv4.OpcodeBootRequest || req.MessageType() == dhcpv4.MessageTypeNone {
+		log.Warnf("invalid message %s %s", req.OpCode, req.MessageType())
+		s.Request.With(prometheus.Labels{"family": "v4", "type": "malformed"}).Inc()
+		return
+	}
+	t := NewTxn4(req, log)
+	s.Request.With(prometheus.Labels{"family": "v4", "type": dhcp.MetricLabel(req.MessageType().String())}).Inc()
+	if s.Standby() {
+		t.Log.Debug("standby, discarded")
+		s.Response.With(prometheus.Labels{"family": "v4", "type": "standby"}).Inc()
+		return
+	}
+	t.Scope = s.Leases.SelectScope(true, t.Relayed(), t.LinkAddr, t.InterfaceID)
+	if t.Scope == nil {
+		t.Log.WithField("circuitID", fmt.Sprintf("%x", t.InterfaceID)).Warn("no network for client")
+	} else {
+		t.Log = t.Log.WithField("network", t.Scope.Name)
+	}
+
+	resp, err := s.Reply4(t)
+	if err != nil {
+		t.Log.Errorf("unable to build response: %v", err)
+		s.Response.With(prometheus.Labels{"family": "v4", "type": "error"}).Inc()
+		return
+	}
+	if resp == nil {
+		t.Log.Info("discarded")
+		s.Response.With(prometheus.Labels{"family": "v4", "type": "discarded"}).Inc()
+		return
+	}
+	t.Log.Trace(resp.Summary())
+	s.Response.With(prometheus.Labels{"family": "v4", "type": dhcp.MetricLabel(resp.MessageType().String())}).Inc()
+	dest := t.ReplyAddr(resp)
+	if _, err := conn.WriteTo(resp.ToBytes(), dest); err != nil {
+		t.Log.Errorf("failed to send %s to %v: %v", resp.MessageType(), dest, err)
+	} else {
+		t.Log.Infof("%s sent to %v", resp.MessageType(), dest)
+	}
+}
+
+// Reply4 builds the response to a DHCPv4 client message as RFC 2131 section
+// 4.3 defines it, a nil response means the message is discarded
+func (s *DhcpServer) Reply4(t *Txn4) (*dhcpv4.DHCPv4, error) {
+	req, scope := t.Msg, t.Scope
+	if sid := req.ServerIdentifier(); sid != nil && !sid.Equal(s.ServerIP) {
+		// the client selected or is bound to another server
+		return nil, nil
+	}
+
+	switch req.MessageType() {
+
+	// DISCOVER
+	case dhcpv4.MessageTypeDiscover:
+		if scope == nil {
+			return nil, nil
+		}
+		b, err := s.Leases.Offer(scope, t.ID, V4_IAID, s.hint4(t), 0, t.ReservedAddr() != nil)
+		if err != nil {
+			t.Log.Warn(err)
+			s.failure4(err)
+			return nil, nil
+		}
+		t.Log.WithFields(BindingFields(b)).Info(b.State.String())
+		return s.ack4(t, dhcpv4.MessageTypeOffer, b)
+
+	// REQUEST
+	case dhcpv4.MessageTypeRequest:
+		if scope == nil {
+			return nil, nil
+		}
+		requested := req.RequestedIPAddress()
+		if requested == nil || requested.IsUnspecified() {
+			// renewing or rebinding, the client is bound to ciaddr
+			requested = req.ClientIPAddr
+		}
+		if requested == nil || requested.IsUnspecified() {
+			return nil, nil
+		}
+		if !scope.OnLink(requested) {
+			return s.nak4(t, "address not on link")
+		}
+		var (
+			b   *Binding
+			err error
+		)
+		if req.ServerIdentifier() == nil && !req.ClientIPAddr.IsUnspecified() {
+			key := ClientKey(t.ID, V4_IAID)
+			if bound := s.Leases.Find(key); bound == nil || !bound.Addr.Equal(requested) {
+				err = ErrNoBinding
+			} else {
+				b, err = s.Leases.Renew(scope, key)
+			}
+		} else if fixed := t.ReservedAddr(); fixed != nil {
+			// a client with a reservation is bound to it and nothing else
+			if !fixed.Equal(requested) {
+				t.Log.WithFields(logrus.Fields{"requested": requested.String(), "reserved": fixed.String()}).Warn("requested address not reserved")
+				return s.nak4(t, "requested address not reserved")
+			}
+			b, err = s.Leases.Commit(scope, t.ID, V4_IAID, fixed, 0, true)
+		} else {
+			// anything but the address the client gets is refused unbound
+			b, err = s.Leases.CommitAddr(scope, t.ID, V4_IAID, requested)
+		}
+		if err != nil {
+			t.Log.WithField("requested", requested.String()).Warn(err)
+			s.failure4(err)
+			return s.nak4(t, err.Error())
+		}
+		t.Log.WithFields(BindingFields(b)).Info(b.State.String())
+		return s.ack4(t, dhcpv4.MessageTypeAck, b)
+
+	// RELEASE
+	case dhcpv4.MessageTypeRelease:
+		key := ClientKey(t.ID, V4_IAID)
+		if b := s.Leases.Find(key); b != nil && b.Addr.Equal(req.ClientIPAddr) {
+			if b, err := s.Leases.Release(key); err == nil {
+				t.Log.WithFields(BindingFields(b)).Info("released")
+			}
+		}
+		return nil, nil
+
+	// DECLINE
+	case dhcpv4.MessageTypeDecline:
+		if b, err := s.Leases.Decline(ClientKey(t.ID, V4_IAID), req.RequestedIPAddress()); err != nil {
+			t.Log.Warn(err)
+		} else {
+			t.Log.WithFields(BindingFields(b)).Info("declined")
+			s.Decline.With(prometheus.Labels{"family": "v4"}).Inc()
+		}
+		return nil, nil
+
+	// INFORM
+	case dhcpv4.MessageTypeInform:
+		resp, err := dhcpv4.NewReplyFromRequest(req,
+			dhcpv4.WithMessageType(dhcpv4.MessageTypeAck),
+			dhcpv4.WithOption(dhcpv4.OptServerIdentifier(s.ServerIP)),
+		)
+		if err != nil {
+			return nil, err
+		}
+		if scope != nil {
+			s.addOptions4(t, resp)
+		}
+		return resp, nil
+	}
+	return nil, nil
+}
+
+// hint4 returns the address the client asks for in a Discover, the reserved
+// one if any
+func (s *DhcpServer) hint4(t *Txn4) net.IP {
+	if ip := t.ReservedAddr(); ip != nil {
+		return ip
+	}
+	if ip := t.Msg.RequestedIPAddress(); ip != nil && !ip.IsUnspecified() {
+		return ip
+	}
+	return nil
+}
+
+// ack4 builds an Offer or an Ack for the binding with the network options
+func (s *DhcpServer) ack4(t *Txn4, mt dhcpv4.MessageType, b *Binding) (*dhcpv4.DHCPv4, error) {
+	resp, err := dhcpv4.NewReplyFromRequest(t.Msg,
+		dhcpv4.WithMessageType(mt),
+		dhcpv4.WithYourIP(b.Addr.To4()),
+		dhcpv4.WithOption(dhcpv4.OptServerIdentifier(s.ServerIP)),
+		dhcpv4.WithOption(dhcpv4.OptIPAddressLeaseTime(b.Valid)),
+		dhcpv4.WithGeneric(dhcpv4.OptionRenewTimeValue, seconds4(b.Valid/2)),
+		dhcpv4.WithGeneric(dhcpv4.OptionRebindingTimeValue, seconds4(b.Valid*7/8)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.addOptions4(t, resp)
+	return resp, nil
+}
+
+// nak4 builds a Nak, broadcast by the relay agent as the client may have no
+// usable address
+func (s *DhcpServer) nak4(t *Txn4, text string) (*dhcpv4.DHCPv4, error) {
+	resp, err := dhcpv4.NewReplyFromRequest(t.Msg,
+		dhcpv4.WithMessageType(dhcpv4.MessageTypeNak),
+		dhcpv4.WithOption(dhcpv4.OptServerIdentifier(s.ServerIP)),
+		dhcpv4.WithOption(dhcpv4.OptMessage(text)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if t.Relayed() {
+		resp.SetBroadcast()
+	}
+	return resp, nil
+}
+
+// addOptions4 adds the subnet mask and router of the network, then the
+// options of the network and reservation the client asked for
+func (s *DhcpServer) addOptions4(t *Txn4, resp *dhcpv4.DHCPv4) {
+	if t.Scope.Mask != nil {
+		resp.UpdateOption(dhcpv4.OptSubnetMask(t.Scope.Mask))
+	}
+	if gw := net.ParseIP(t.Scope.Network.GetGateway()).To4(); gw != nil && Requested4(t.Msg, dhcpv4.OptionRouter) {
+		resp.UpdateOption(dhcpv4.OptRouter(gw))
+	}
+	AddRequestedOptions4(t.Msg, resp, t.Options())
+}
+
+// failure4 counts an address refused to a DHCPv4 client
+func (s *DhcpServer) failure4(err error) {
+	status := iana.StatusUnspecFail
+	switch err {
+	case ErrNoAddrsAvail:
+		status = iana.StatusNoAddrsAvail
+	case ErrNoBinding:
+		status = iana.StatusNoBinding
+	case ErrNotOnLink:
+		status = iana.StatusNotOnLink
+	case ErrConflict:
+		s.Conflict.With(prometheus.Labels{"family": "v4"}).Inc()
+	}
+	s.Failure.With(prometheus.Labels{"family": "v4", "status": dhcp.MetricLabel(status.String())}).Inc()
+}
+
+func seconds4(d time.Duration) []byte {
+	sec := uint32(d / time.Second)
+	return []byte{byte(sec >> 24), byte(sec >> 16), byte(sec >> 8), byte(sec)}
+}
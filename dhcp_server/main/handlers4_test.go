@@ -0,0 +1,266 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/polarbroadband/rp1/proto/dhcp"
+)
+
+var (
+	testMAC         = net.HardwareAddr{0x02, 0, 0, 0, 0, 0x02}
+	testReservedMAC = net.HardwareAddr{0x02, 0, 0, 0, 0, 0x03}
+)
+
+func testConfig4() *dhcp.Config {
+	return &dhcp.Config{Networks: map[string]*dhcp.Network{
+		"lan4": {
+			Prefix:       "10.8.0.0/24",
+			Gateway:      "10.8.0.1",
+			Pools:        []*dhcp.Pool{{Begin: "10.8.0.100", End: "10.8.0.200"}},
+			Reservations: []*dhcp.Reservation{{Name: "printer", MAC: testReservedMAC.String(), Addr: "10.8.0.50"}},
+		},
+	}}
+}
+
+func newTestServer(t *testing.T, cfg *dhcp.Config) *DhcpServer {
+	t.Helper()
+	db, err := NewLeaseDB(cfg, testLog())
+	if err != nil {
+		t.Fatalf("NewLeaseDB: %v", err)
+	}
+	return &DhcpServer{
+		DUID:     testServerDUID,
+		ServerIP: net.ParseIP("10.8.0.2"),
+		Leases:   db,
+		Metrics:  NewMetrics(prometheus.NewRegistry(), db),
+		Log:      testLog(),
+	}
+}
+
+// reply4 serves a DHCPv4 client message on the link of the server
+func reply4(t *testing.T, s *DhcpServer, msg *dhcpv4.DHCPv4) *dhcpv4.DHCPv4 {
+	t.Helper()
+	txn := NewTxn4(msg, s.Log)
+	txn.Scope = s.Leases.SelectScope(true, txn.Relayed(), txn.LinkAddr, txn.InterfaceID)
+	resp, err := s.Reply4(txn)
+	if err != nil {
+		t.Fatalf("%s: %v", msg.MessageType(), err)
+	}
+	return resp
+}
+
+func clientMessage4(t *testing.T, mt dhcpv4.MessageType, mac net.HardwareAddr, modifiers ...dhcpv4.Modifier) *dhcpv4.DHCPv4 {
+	t.Helper()
+	m, err := dhcpv4.New(append([]dhcpv4.Modifier{dhcpv4.WithMessageType(mt), dhcpv4.WithHwAddr(mac)}, modifiers...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestReply4Request(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		mac  net.HardwareAddr
+		// address bound to the client beforehand, if any
+		bound     string
+		requested string
+		// renewing from the bound address, without server identifier
+		renew bool
+		ack   bool
+	}{
+		{"pool address", testMAC, "", "10.8.0.150", false, true},
+		{"gateway", testMAC, "", "10.8.0.1", false, false},
+		{"off link", testMAC, "", "192.168.1.10", false, false},
+		{"reserved address", testReservedMAC, "", "10.8.0.50", false, true},
+		{"reserved client asks for the gateway", testReservedMAC, "", "10.8.0.1", false, false},
+		{"reserved client asks for a pool address", testReservedMAC, "", "10.8.0.150", false, false},
+		{"reserved address asked by another client", testMAC, "", "10.8.0.50", false, false},
+		{"bound address", testMAC, "10.8.0.150", "10.8.0.150", false, true},
+		{"bound client asks for another address", testMAC, "10.8.0.150", "10.8.0.151", false, false},
+		{"bound client asks for the gateway", testMAC, "10.8.0.150", "10.8.0.1", false, false},
+		{"renewing", testMAC, "10.8.0.150", "10.8.0.150", true, true},
+		{"renewing another address", testMAC, "10.8.0.150", "10.8.0.151", true, false},
+		{"renewing unbound", testMAC, "", "10.8.0.150", true, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestServer(t, testConfig4())
+			if tc.bound != "" {
+				ack := reply4(t, s, clientMessage4(t, dhcpv4.MessageTypeRequest, tc.mac,
+					dhcpv4.WithOption(dhcpv4.OptRequestedIPAddress(net.ParseIP(tc.bound))),
+					dhcpv4.WithOption(dhcpv4.OptServerIdentifier(s.ServerIP)),
+				))
+				if ack == nil || ack.MessageType() != dhcpv4.MessageTypeAck {
+					t.Fatalf("got %v, want ACK of %s", ack, tc.bound)
+				}
+			}
+			before := map[string]Binding{}
+			for k, b := range s.Leases.Bindings {
+				before[k] = *b
+			}
+			requested := net.ParseIP(tc.requested)
+			mods := []dhcpv4.Modifier{dhcpv4.WithOption(dhcpv4.OptRequestedIPAddress(requested)), dhcpv4.WithOption(dhcpv4.OptServerIdentifier(s.ServerIP))}
+			if tc.renew {
+				mods = []dhcpv4.Modifier{dhcpv4.WithClientIP(requested)}
+			}
+			resp := reply4(t, s, clientMessage4(t, dhcpv4.MessageTypeRequest, tc.mac, mods...))
+			if resp == nil {
+				t.Fatal("no response")
+			}
+			if !tc.ack {
+				if resp.MessageType() != dhcpv4.MessageTypeNak {
+					t.Errorf("%s %v, want NAK", resp.MessageType(), resp.YourIPAddr)
+				}
+				// a refused request leaves the bindings as they were
+				if len(s.Leases.Bindings) != len(before) {
+					t.Errorf("bindings %v, want %v", s.Leases.Bindings, before)
+				}
+				for k, b := range s.Leases.Bindings {
+					if was, ok := before[k]; !ok || !b.Addr.Equal(was.Addr) || b.State != was.State || !b.Expire.Equal(was.Expire) {
+						t.Errorf("binding %v, was %v", b, was)
+					}
+				}
+				return
+			}
+			if resp.MessageType() != dhcpv4.MessageTypeAck || !resp.YourIPAddr.Equal(requested) {
+				t.Fatalf("%s %v, want ACK %v", resp.MessageType(), resp.YourIPAddr, requested)
+			}
+			if b := s.Leases.Holder(requested); b == nil || b.State != StateBound {
+				t.Errorf("%v not bound: %v", requested, b)
+			}
+		})
+	}
+}
+
+func TestReply4Discover(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		mac       net.HardwareAddr
+		requested string
+		want      string
+	}{
+		{"first free", testMAC, "", "10.8.0.100"},
+		{"requested", testMAC, "10.8.0.150", "10.8.0.150"},
+		{"requested out of pool", testMAC, "10.8.0.1", "10.8.0.100"},
+		{"requested reserved", testMAC, "10.8.0.50", "10.8.0.100"},
+		{"reservation", testReservedMAC, "", "10.8.0.50"},
+		{"reservation over requested", testReservedMAC, "10.8.0.150", "10.8.0.50"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestServer(t, testConfig4())
+			mods := []dhcpv4.Modifier{dhcpv4.WithRequestedOptions(dhcpv4.OptionRouter)}
+			if tc.requested != "" {
+				mods = append(mods, dhcpv4.WithOption(dhcpv4.OptRequestedIPAddress(net.ParseIP(tc.requested))))
+			}
+			resp := reply4(t, s, clientMessage4(t, dhcpv4.MessageTypeDiscover, tc.mac, mods...))
+			if resp == nil || resp.MessageType() != dhcpv4.MessageTypeOffer {
+				t.Fatalf("got %v, want OFFER", resp)
+			}
+			if !resp.YourIPAddr.Equal(net.ParseIP(tc.want)) {
+				t.Errorf("offered %v, want %s", resp.YourIPAddr, tc.want)
+			}
+			if !resp.ServerIdentifier().Equal(s.ServerIP) {
+				t.Errorf("server identifier %v, want %v", resp.ServerIdentifier(), s.ServerIP)
+			}
+			if r := dhcpv4.GetIP(dhcpv4.OptionRouter, resp.Options); !r.Equal(net.ParseIP("10.8.0.1")) {
+				t.Errorf("router %v, want the gateway", r)
+			}
+			if b := s.Leases.Holder(resp.YourIPAddr); b == nil || b.State != StateOffered {
+				t.Errorf("%v not offered: %v", resp.YourIPAddr, b)
+			}
+		})
+	}
+}
+
+func TestReply4OtherServer(t *testing.T) {
+	s := newTestServer(t, testConfig4())
+	resp := reply4(t, s, clientMessage4(t, dhcpv4.MessageTypeRequest, testMAC,
+		dhcpv4.WithOption(dhcpv4.OptRequestedIPAddress(net.ParseIP("10.8.0.150"))),
+		dhcpv4.WithOption(dhcpv4.OptServerIdentifier(net.ParseIP("10.8.0.3"))),
+	))
+	if resp != nil {
+		t.Errorf("got %s, want the request to another server discarded", resp.MessageType())
+	}
+	if b := s.Leases.Holder(net.ParseIP("10.8.0.150")); b != nil {
+		t.Errorf("bound %v", b)
+	}
+}
+
+func TestReply4ReleaseDecline(t *testing.T) {
+	addr := net.ParseIP("10.8.0.150")
+	for _, tc := range []struct {
+		mt   dhcpv4.MessageType
+		mods []dhcpv4.Modifier
+		// binding of the address afterwards, nil if free
+		holder *BindingState
+	}{
+		{dhcpv4.MessageTypeRelease, []dhcpv4.Modifier{dhcpv4.WithClientIP(addr)}, nil},
+		{dhcpv4.MessageTypeRelease, []dhcpv4.Modifier{dhcpv4.WithClientIP(net.ParseIP("10.8.0.151"))}, state(StateBound)},
+		{dhcpv4.MessageTypeDecline, []dhcpv4.Modifier{dhcpv4.WithOption(dhcpv4.OptRequestedIPAddress(addr))}, state(StateDeclined)},
+	} {
+		t.Run(tc.mt.String(), func(t *testing.T) {
+			s := newTestServer(t, testConfig4())
+			ack := reply4(t, s, clientMessage4(t, dhcpv4.MessageTypeRequest, testMAC,
+				dhcpv4.WithOption(dhcpv4.OptRequestedIPAddress(addr)),
+				dhcpv4.WithOption(dhcpv4.OptServerIdentifier(s.ServerIP)),
+			))
+			if ack == nil || ack.MessageType() != dhcpv4.MessageTypeAck {
+				t.Fatalf("got %v, want ACK", ack)
+			}
+			mods := append([]dhcpv4.Modifier{dhcpv4.WithOption(dhcpv4.OptServerIdentifier(s.ServerIP))}, tc.mods...)
+			if resp := reply4(t, s, clientMessage4(t, tc.mt, testMAC, mods...)); resp != nil {
+				t.Errorf("got %s, want no response", resp.MessageType())
+			}
+			h := s.Leases.Holder(addr)
+			if tc.holder == nil {
+				if h != nil {
+					t.Errorf("%v held by %v, want free", addr, h)
+				}
+				return
+			}
+			if h == nil || h.State != *tc.holder {
+				t.Errorf("%v held by %v, want %v", addr, h, *tc.holder)
+			}
+		})
+	}
+}
+
+func TestReplyAddr4(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		giaddr string
+		ciaddr string
+		resp   dhcpv4.MessageType
+		want   string
+	}{
+		{"offer", "", "", dhcpv4.MessageTypeOffer, "255.255.255.255:68"},
+		{"ack of a selecting client", "", "", dhcpv4.MessageTypeAck, "255.255.255.255:68"},
+		{"ack of a renewing client", "", "10.8.0.150", dhcpv4.MessageTypeAck, "10.8.0.150:68"},
+		{"nak of a renewing client", "", "10.8.0.150", dhcpv4.MessageTypeNak, "255.255.255.255:68"},
+		{"relayed offer", "10.9.0.1", "", dhcpv4.MessageTypeOffer, "10.9.0.1:67"},
+		{"relayed ack of a renewing client", "10.9.0.1", "10.9.0.150", dhcpv4.MessageTypeAck, "10.9.0.1:67"},
+		{"relayed nak", "10.9.0.1", "", dhcpv4.MessageTypeNak, "10.9.0.1:67"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mods := []dhcpv4.Modifier{}
+			if tc.giaddr != "" {
+				mods = append(mods, dhcpv4.WithGatewayIP(net.ParseIP(tc.giaddr)))
+			}
+			if tc.ciaddr != "" {
+				mods = append(mods, dhcpv4.WithClientIP(net.ParseIP(tc.ciaddr)))
+			}
+			req := clientMessage4(t, dhcpv4.MessageTypeRequest, testMAC, mods...)
+			resp, err := dhcpv4.NewReplyFromRequest(req, dhcpv4.WithMessageType(tc.resp))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := NewTxn4(req, testLog()).ReplyAddr(resp).String(); got != tc.want {
+				t.Errorf("sent to %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
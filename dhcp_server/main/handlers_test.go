@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/iana"
+
+	"github.com/polarbroadband/rp1/proto/dhcp"
+)
+
+var (
+	testServerDUID = dhcpv6.Duid{Type: dhcpv6.DUID_LL, HwType: iana.HWTypeEthernet, LinkLayerAddr: net.HardwareAddr{0x02, 0, 0, 0, 0, 0x01}}
+	testClientDUID = dhcpv6.Duid{Type: dhcpv6.DUID_LL, HwType: iana.HWTypeEthernet, LinkLayerAddr: net.HardwareAddr{0x02, 0, 0, 0, 0, 0x02}}
+	testIAID       = [4]byte{0, 0, 0, 1}
+)
+
+// reply6 serves a client message on the link of the server
+func reply6(t *testing.T, s *DhcpServer, msg *dhcpv6.Message) *dhcpv6.Message {
+	t.Helper()
+	txn, err := NewTxn(msg, s.Log)
+	if err != nil {
+		t.Fatalf("NewTxn: %v", err)
+	}
+	txn.Scope = s.Leases.SelectScope(false, txn.Relayed(), txn.LinkAddr, txn.InterfaceID)
+	resp, err := s.Reply(txn)
+	if err != nil {
+		t.Fatalf("%s: %v", msg.Type(), err)
+	}
+	return resp
+}
+
+func clientMessage(mt dhcpv6.MessageType, opts ...dhcpv6.Option) *dhcpv6.Message {
+	msg := &dhcpv6.Message{MessageType: mt, TransactionID: dhcpv6.TransactionID{1, 2, 3}}
+	msg.AddOption(dhcpv6.OptClientID(testClientDUID))
+	for _, o := range opts {
+		msg.AddOption(o)
+	}
+	return msg
+}
+
+func TestReplyReleaseDecline(t *testing.T) {
+	cfg := &dhcp.Config{Networks: map[string]*dhcp.Network{
+		"lan": {Prefix: "2001:db8::/64", Pools: []*dhcp.Pool{{Begin: "2001:db8::10", End: "2001:db8::20"}}},
+	}}
+	for _, tc := range []struct {
+		mt    dhcpv6.MessageType
+		state BindingState
+		held  bool
+	}{
+		{dhcpv6.MessageTypeRelease, StateBound, false},
+		{dhcpv6.MessageTypeDecline, StateDeclined, true},
+	} {
+		t.Run(tc.mt.String(), func(t *testing.T) {
+			s := newTestServer(t, cfg)
+			reply := reply6(t, s, clientMessage(dhcpv6.MessageTypeRequest,
+				dhcpv6.OptServerID(testServerDUID), &dhcpv6.OptIANA{IaId: testIAID}))
+			addr := reply.Options.OneIANA().Options.OneAddress()
+			if addr == nil {
+				t.Fatalf("request got no address: %s", reply.Summary())
+			}
+
+			ia := &dhcpv6.OptIANA{IaId: testIAID}
+			ia.Options.Add(&dhcpv6.OptIAAddress{IPv6Addr: addr.IPv6Addr})
+			reply = reply6(t, s, clientMessage(tc.mt, dhcpv6.OptServerID(testServerDUID), ia))
+			if reply == nil || reply.Type() != dhcpv6.MessageTypeReply {
+				t.Fatalf("%s got no reply", tc.mt)
+			}
+			if st := reply.Options.Status(); st == nil || st.StatusCode != iana.StatusSuccess {
+				t.Errorf("%s status %v, want success", tc.mt, st)
+			}
+			if st := reply.Options.OneIANA().Options.Status(); st == nil || st.StatusCode != iana.StatusSuccess {
+				t.Errorf("%s IA status %v, want success", tc.mt, st)
+			}
+			if !testServerDUID.Equal(*reply.Options.ServerID()) || !testClientDUID.Equal(*reply.Options.ClientID()) {
+				t.Errorf("%s reply identifiers %v %v", tc.mt, reply.Options.ServerID(), reply.Options.ClientID())
+			}
+			if b := s.Leases.Find(ClientKey(DuidString(&testClientDUID), IaidString(testIAID))); b != nil {
+				t.Errorf("%s left binding %v", tc.mt, b)
+			}
+			b := s.Leases.Holder(addr.IPv6Addr)
+			if (b != nil) != tc.held {
+				t.Fatalf("%s address held %v, want %v", tc.mt, b, tc.held)
+			}
+			if b != nil && b.State != tc.state {
+				t.Errorf("%s address state %v, want %v", tc.mt, b.State, tc.state)
+			}
+		})
+	}
+}
+
+func TestReplyDeclineStatus(t *testing.T) {
+	cfg := &dhcp.Config{Networks: map[string]*dhcp.Network{
+		"lan": {Prefix: "2001:db8::/64", Pools: []*dhcp.Pool{{Begin: "2001:db8::10", End: "2001:db8::20"}}},
+	}}
+	for _, tc := range []struct {
+		name string
+		// addresses declined, the empty one is the bound address
+		addrs []string
+		want  iana.StatusCode
+	}{
+		{"bound address", []string{""}, iana.StatusSuccess},
+		{"unbound then bound address", []string{"2001:db8::1f", ""}, iana.StatusNoBinding},
+		{"unbound address", []string{"2001:db8::1f"}, iana.StatusNoBinding},
+		{"no address", nil, iana.StatusNoBinding},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestServer(t, cfg)
+			reply := reply6(t, s, clientMessage(dhcpv6.MessageTypeRequest,
+				dhcpv6.OptServerID(testServerDUID), &dhcpv6.OptIANA{IaId: testIAID}))
+			addr := reply.Options.OneIANA().Options.OneAddress()
+			if addr == nil {
+				t.Fatalf("request got no address: %s", reply.Summary())
+			}
+
+			ia := &dhcpv6.OptIANA{IaId: testIAID}
+			bound := false
+			for _, a := range tc.addrs {
+				ip := net.ParseIP(a)
+				if a == "" {
+					ip, bound = addr.IPv6Addr, true
+				}
+				ia.Options.Add(&dhcpv6.OptIAAddress{IPv6Addr: ip})
+			}
+			reply = reply6(t, s, clientMessage(dhcpv6.MessageTypeDecline, dhcpv6.OptServerID(testServerDUID), ia))
+			if reply == nil || len(reply.Options.IANA()) != 1 {
+				t.Fatalf("got %v, want one IA", reply)
+			}
+			if st := reply.Options.OneIANA().Options.Status(); st == nil || st.StatusCode != tc.want {
+				t.Errorf("IA status %v, want %s", st, tc.want)
+			}
+			if b := s.Leases.Holder(addr.IPv6Addr); bound != (b != nil && b.State == StateDeclined) {
+				t.Errorf("bound address %v, want declined %v", b, bound)
+			}
+		})
+	}
+}
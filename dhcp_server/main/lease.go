@@ -0,0 +1,674 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/polarbroadband/rp1/etcdlib"
+	"github.com/polarbroadband/rp1/proto/dhcp"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	DEFAULT_VALID_LIFETIME     = time.Hour * 2
+	DEFAULT_PREFERRED_LIFETIME = time.Hour
+	OFFER_HOLD_TIME            = time.Minute
+	DECLINE_HOLD_TIME          = time.Hour
+)
+
+var (
+	ErrNoAddrsAvail  = errors.New("no address available")
+	ErrNoPrefixAvail = errors.New("no prefix available")
+	ErrConflict      = errors.New("address is bound to another client")
+	ErrNoBinding     = errors.New("no binding for client")
+	ErrNotOnLink     = errors.New("address not on link")
+	ErrUnavailable   = errors.New("requested address not available")
+)
+
+type BindingState int
+
+const (
+	StateOffered BindingState = iota
+	StateBound
+	StateDeclined
+)
+
+func (s BindingState) String() string {
+	switch s {
+	case StateOffered:
+		return "offered"
+	case StateBound:
+		return "bound"
+	case StateDeclined:
+		return "declined"
+	}
+	return "unknown"
+}
+
+// Binding is an address or, with PrefixLen set, a delegated prefix held by
+// one IA of one client. A declined address is held out of the pools by the
+// binding of the declining client until expiry
+type Binding struct {
+	Network   string
+	DUID      string
+	IAID      string
+	Addr      net.IP
+	PrefixLen int
+	Valid     time.Duration
+	Preferred time.Duration
+	Expire    time.Time
+	State     BindingState
+	// key authenticating Reconfigure messages, if the client accepts them
+	ReconfigureKey []byte
+	// etcd lease expiring the saved binding, 0 if not saved
+	Lease int64
+}
+
+func (b *Binding) Key() string {
+	if b.PrefixLen > 0 {
+		return PrefixKey(b.DUID, b.IAID)
+	}
+	return ClientKey(b.DUID, b.IAID)
+}
+
+func (b *Binding) AddrKey() string {
+	return AddrKey(b.Addr, b.PrefixLen)
+}
+
+// Prefix returns the delegated prefix, nil for an address binding
+func (b *Binding) Prefix() *net.IPNet {
+	if b.PrefixLen == 0 {
+		return nil
+	}
+	return &net.IPNet{IP: b.Addr, Mask: net.CIDRMask(b.PrefixLen, 128)}
+}
+
+func (b *Binding) Expired(now time.Time) bool {
+	return !now.Before(b.Expire)
+}
+
+func (b *Binding) String() string {
+	return fmt.Sprintf("%s DUID: %s IAID: %s Addr: %v State: %v Expire: %v", b.Network, b.DUID, b.IAID, b.AddrKey(), b.State, b.Expire.Format(time.RFC3339))
+}
+
+// ClientKey identifies the IA_NA of a client, bindings are sticky on it
+func ClientKey(duid, iaid string) string {
+	return duid + "/" + iaid
+}
+
+// PrefixKey identifies the IA_PD of a client
+func PrefixKey(duid, iaid string) string {
+	return ClientKey(duid, iaid) + "/pd"
+}
+
+// AddrKey identifies an address, or a prefix if plen is set
+func AddrKey(ip net.IP, plen int) string {
+	if plen > 0 {
+		return fmt.Sprintf("%v/%d", ip, plen)
+	}
+	return ip.String()
+}
+
+// DuidString returns the hex form of a client DUID used as binding identity
+func DuidString(duid *dhcpv6.Duid) string {
+	if duid == nil {
+		return ""
+	}
+	return hex.EncodeToString(duid.ToBytes())
+}
+
+// IaidString returns the hex form of an IAID
+func IaidString(iaid [4]byte) string {
+	return hex.EncodeToString(iaid[:])
+}
+
+type AddrRange struct {
+	Begin net.IP
+	End   net.IP
+}
+
+func (r AddrRange) Contains(ip net.IP) bool {
+	ip = ip.To16()
+	return ip != nil && bytes.Compare(ip, r.Begin) >= 0 && bytes.Compare(ip, r.End) <= 0
+}
+
+// Size returns the number of addresses of the range
+func (r AddrRange) Size() float64 {
+	n := new(big.Int).Sub(new(big.Int).SetBytes(r.End), new(big.Int).SetBytes(r.Begin))
+	size, _ := new(big.Float).SetInt(n.Add(n, big.NewInt(1))).Float64()
+	return size
+}
+
+// NextIP returns the address following ip
+func NextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+// NextPrefix returns the prefix of length plen following the one at ip
+func NextPrefix(ip net.IP, plen int) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	i := (plen - 1) / 8
+	carry := uint(next[i]) + 1<<(7-uint(plen-1)%8)
+	next[i] = byte(carry)
+	for carry > 0xff && i > 0 {
+		i--
+		carry = uint(next[i]) + 1
+		next[i] = byte(carry)
+	}
+	return next
+}
+
+// LastAddr returns the last address of the prefix of length plen at ip
+func LastAddr(ip net.IP, plen int) net.IP {
+	last := make(net.IP, len(ip))
+	mask := net.CIDRMask(plen, 8*len(ip))
+	for i := range ip {
+		last[i] = ip[i] | ^mask[i]
+	}
+	return last
+}
+
+// Scope is the runtime form of a dhcp.Network, with parsed pools and lifetimes.
+// Prefixes of length Delegated are delegated out of Prefix, clear of the pools
+type Scope struct {
+	Name      string
+	Network   *dhcp.Network
+	Prefix    *net.IPNet
+	Pools     []AddrRange
+	Reserved  map[string]*dhcp.Reservation // by address or prefix, see AddrKey
+	Mask      net.IPMask                   // DHCPv4 subnet mask
+	Delegated int
+	Valid     time.Duration
+	Preferred time.Duration
+}
+
+func NewScope(name string, n *dhcp.Network) (*Scope, error) {
+	if err := n.Validate(name); err != nil {
+		return nil, err
+	}
+	s := Scope{
+		Name:      name,
+		Network:   n,
+		Reserved:  map[string]*dhcp.Reservation{},
+		Delegated: int(n.GetDelegatedLength()),
+		Valid:     DEFAULT_VALID_LIFETIME,
+		Preferred: DEFAULT_PREFERRED_LIFETIME,
+	}
+	for _, r := range n.GetReservations() {
+		if ip := net.ParseIP(r.GetAddr()); ip != nil {
+			s.Reserved[AddrKey(ip, 0)] = r
+		}
+		if ip, p, err := net.ParseCIDR(r.GetPrefix()); err == nil {
+			ones, _ := p.Mask.Size()
+			s.Reserved[AddrKey(ip, ones)] = r
+		}
+	}
+	if n.GetPrefix() != "" {
+		_, s.Prefix, _ = net.ParseCIDR(n.GetPrefix())
+	}
+	for _, p := range n.GetPools() {
+		s.Pools = append(s.Pools, AddrRange{net.ParseIP(p.GetBegin()).To16(), net.ParseIP(p.GetEnd()).To16()})
+	}
+	if n.IPv4() {
+		if s.Prefix != nil {
+			s.Mask = s.Prefix.Mask
+		}
+		if n.GetSubnetMask() != "" {
+			s.Mask = net.IPMask(net.ParseIP(n.GetSubnetMask()).To4())
+		}
+		// the subnet of the pools when only the mask is set
+		if s.Prefix == nil && len(s.Pools) > 0 {
+			s.Prefix = &net.IPNet{IP: s.Pools[0].Begin.To4().Mask(s.Mask), Mask: s.Mask}
+		}
+	}
+	if n.GetValidLifetime() > 0 {
+		s.Valid = time.Duration(n.GetValidLifetime()) * time.Second
+	}
+	if n.GetPreferredLifetime() > 0 {
+		s.Preferred = time.Duration(n.GetPreferredLifetime()) * time.Second
+	}
+	if s.Preferred > s.Valid {
+		return nil, fmt.Errorf("network %s preferred lifetime %v exceeds valid lifetime %v", name, s.Preferred, s.Valid)
+	}
+	return &s, nil
+}
+
+// IPv4 reports whether the scope is served by DHCPv4
+func (s *Scope) IPv4() bool {
+	return s.Network.IPv4()
+}
+
+func (s *Scope) InPool(ip net.IP) bool {
+	for _, r := range s.Pools {
+		if r.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Assignable reports whether the address, or the prefix if plen is set, can
+// be handed out in the scope, either dynamically or by reservation
+func (s *Scope) Assignable(ip net.IP, plen int) bool {
+	if _, ok := s.Reserved[AddrKey(ip, plen)]; ok {
+		return true
+	}
+	if plen == 0 {
+		return s.InPool(ip)
+	}
+	ip = ip.To16()
+	return ip != nil && plen == s.Delegated && s.Prefix.Contains(ip) &&
+		ip.Equal(ip.Mask(net.CIDRMask(plen, 128))) && !s.overlapPools(ip, plen)
+}
+
+func (s *Scope) overlapPools(ip net.IP, plen int) bool {
+	last := LastAddr(ip, plen)
+	for _, r := range s.Pools {
+		if bytes.Compare(ip, r.End) <= 0 && bytes.Compare(last, r.Begin) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// LeaseDB allocates addresses out of the pools and prefixes out of the
+// delegation prefix of every configured network and keeps track of the
+// bindings made, bound ones are saved in Depot if set, only while leading the
+// Election if set
+type LeaseDB struct {
+	Locker   *sync.RWMutex
+	Scopes   map[string]*Scope
+	Bindings map[string]*Binding // by client DUID/IAID, see ClientKey and PrefixKey
+	Addrs    map[string]*Binding // by address or prefix, see AddrKey
+	Depot    *etcdlib.KvDepot
+	Election *Election
+	Log      *logrus.Entry
+}
+
+func NewLeaseDB(cfg *dhcp.Config, log *logrus.Entry) (*LeaseDB, error) {
+	db := LeaseDB{
+		Locker:   &sync.RWMutex{},
+		Scopes:   map[string]*Scope{},
+		Bindings: map[string]*Binding{},
+		Addrs:    map[string]*Binding{},
+		Log:      log,
+	}
+	if err := db.Update(cfg); err != nil {
+		return nil, err
+	}
+	return &db, nil
+}
+
+// Update replaces the networks in one step, existing bindings are kept. The
+// config is refused if an active binding would no longer fit its network
+func (db *LeaseDB) Update(cfg *dhcp.Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	scopes := map[string]*Scope{}
+	for name, n := range cfg.GetNetworks() {
+		s, err := NewScope(name, n)
+		if err != nil {
+			return err
+		}
+		scopes[name] = s
+	}
+
+	db.Locker.Lock()
+	defer db.Locker.Unlock()
+	now := time.Now()
+	for _, b := range db.Addrs {
+		if b.State != StateBound || b.Expired(now) {
+			continue
+		}
+		if s, ok := scopes[b.Network]; !ok || !s.Assignable(b.Addr, b.PrefixLen) {
+			return fmt.Errorf("active binding %v would be orphaned", b)
+		}
+	}
+	db.Scopes = scopes
+	return nil
+}
+
+// DefaultScope returns the first network of the address family by name, nil
+// if none configured
+func (db *LeaseDB) DefaultScope(v4 bool) *Scope {
+	db.Locker.RLock()
+	defer db.Locker.RUnlock()
+	names := []string{}
+	for name, s := range db.Scopes {
+		if s.IPv4() == v4 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return db.Scopes[names[0]]
+}
+
+// SelectScope returns the network of the address family of a relayed client,
+// the one whose link address is the relay link-address, or else whose prefix
+// is the longest to contain it, or else the one of the relay interface-id.
+// Clients on the link of the server get the default network
+func (db *LeaseDB) SelectScope(v4, relayed bool, linkAddr net.IP, ifid []byte) *Scope {
+	if !relayed {
+		return db.DefaultScope(v4)
+	}
+	db.Locker.RLock()
+	defer db.Locker.RUnlock()
+	if linkAddr != nil && !linkAddr.IsUnspecified() && !linkAddr.IsLinkLocalUnicast() {
+		var best *Scope
+		bestLen := -1
+		for _, s := range db.Scopes {
+			if s.IPv4() != v4 {
+				continue
+			}
+			if ip := net.ParseIP(s.Network.GetLinkAddr()); ip != nil && ip.Equal(linkAddr) {
+				return s
+			}
+			if s.Prefix != nil && s.Prefix.Contains(linkAddr) {
+				// ties are broken by name to be stable across replicas
+				if ones, _ := s.Prefix.Mask.Size(); ones > bestLen || (ones == bestLen && s.Name < best.Name) {
+					best, bestLen = s, ones
+				}
+			}
+		}
+		if best != nil {
+			return best
+		}
+	}
+	if len(ifid) > 0 {
+		for _, s := range db.Scopes {
+			if s.IPv4() != v4 {
+				continue
+			}
+			for _, id := range s.Network.GetInterfaceIDs() {
+				if b, err := hex.DecodeString(id); err == nil && bytes.Equal(b, ifid) {
+					return s
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Offer reserves an address, or a prefix if plen is the delegated length of
+// the scope, for the client IA for OFFER_HOLD_TIME. With fixed set, the hint
+// is the address or prefix reserved to the client
+func (db *LeaseDB) Offer(s *Scope, duid, iaid string, hint net.IP, plen int, fixed bool) (*Binding, error) {
+	db.Locker.Lock()
+	defer db.Locker.Unlock()
+	b, err := db.allocate(s, duid, iaid, hint, plen, fixed)
+	if err != nil {
+		return nil, err
+	}
+	if b.State != StateBound {
+		b.Expire = time.Now().Add(OFFER_HOLD_TIME)
+	}
+	return b, nil
+}
+
+// Commit binds an address, or a prefix if plen is the delegated length of the
+// scope, to the client IA for the valid lifetime of the network. With fixed
+// set, the hint is the address or prefix reserved to the client
+func (db *LeaseDB) Commit(s *Scope, duid, iaid string, hint net.IP, plen int, fixed bool) (*Binding, error) {
+	db.Locker.Lock()
+	defer db.Locker.Unlock()
+	return db.commit(s, duid, iaid, hint, plen, fixed)
+}
+
+// CommitAddr binds the address to the client IA as Commit does, only if it is
+// the one the IA gets. ErrUnavailable is returned otherwise and nothing changes
+func (db *LeaseDB) CommitAddr(s *Scope, duid, iaid string, addr net.IP) (*Binding, error) {
+	db.Locker.Lock()
+	defer db.Locker.Unlock()
+	if b, ok := db.Bindings[ClientKey(duid, iaid)]; ok && db.reusable(s, b, addr, 0, false) {
+		if !b.Addr.Equal(addr) {
+			return nil, ErrUnavailable
+		}
+	} else if _, reserved := s.Reserved[AddrKey(addr, 0)]; reserved || !s.Assignable(addr, 0) || !db.available(AddrKey(addr, 0), time.Now()) {
+		return nil, ErrUnavailable
+	}
+	return db.commit(s, duid, iaid, addr, 0, false)
+}
+
+// commit is Commit, the caller must hold the lock
+func (db *LeaseDB) commit(s *Scope, duid, iaid string, hint net.IP, plen int, fixed bool) (*Binding, error) {
+	b, err := db.allocate(s, duid, iaid, hint, plen, fixed)
+	if err != nil {
+		return nil, err
+	}
+	state, expire := b.State, b.Expire
+	b.State = StateBound
+	b.Expire = time.Now().Add(s.Valid)
+	if err := db.persist(b); err != nil {
+		b.State, b.Expire = state, expire
+		return nil, err
+	}
+	return b, nil
+}
+
+// allocate finds the address, or the prefix if plen is set, for a client IA,
+// the caller must hold the lock. A fixed hint is the only candidate, otherwise
+// an existing binding of the IA is reused, then the hinted one, then the first
+// free one of the scope. Reserved ones are never handed out dynamically
+func (db *LeaseDB) allocate(s *Scope, duid, iaid string, hint net.IP, plen int, fixed bool) (*Binding, error) {
+	now := time.Now()
+	key := ClientKey(duid, iaid)
+	if plen > 0 {
+		key = PrefixKey(duid, iaid)
+		if plen != s.Delegated && !fixed {
+			return nil, ErrNoPrefixAvail
+		}
+	}
+	if b, ok := db.Bindings[key]; ok {
+		if db.reusable(s, b, hint, plen, fixed) {
+			db.Addrs[b.AddrKey()] = b
+			if b.Expired(now) {
+				b.State = StateOffered
+			}
+			b.Valid, b.Preferred = s.Valid, s.Preferred
+			return b, nil
+		}
+		db.unpersist(b)
+		db.release(b)
+	}
+
+	var addr net.IP
+	_, reserved := s.Reserved[AddrKey(hint, plen)]
+	if fixed {
+		if !db.available(AddrKey(hint, plen), now) {
+			return nil, ErrConflict
+		}
+		addr = hint.To16()
+	} else if hint != nil && !reserved && s.Assignable(hint, plen) && db.available(AddrKey(hint, plen), now) {
+		addr = hint.To16()
+	} else if plen == 0 {
+		for _, r := range s.Pools {
+			for ip := r.Begin; r.Contains(ip); ip = NextIP(ip) {
+				if db.available(AddrKey(ip, 0), now) {
+					addr = ip
+					break
+				}
+				if ip.Equal(r.End) {
+					break
+				}
+			}
+			if addr != nil {
+				break
+			}
+		}
+	} else {
+		for ip := s.Prefix.IP.To16(); s.Prefix.Contains(ip); ip = NextPrefix(ip, plen) {
+			_, reserved := s.Reserved[AddrKey(ip, plen)]
+			if !reserved && !s.overlapPools(ip, plen) && db.available(AddrKey(ip, plen), now) {
+				addr = ip
+				break
+			}
+			if bytes.Compare(NextPrefix(ip, plen), ip) <= 0 {
+				break
+			}
+		}
+	}
+	if addr == nil {
+		if plen > 0 {
+			return nil, ErrNoPrefixAvail
+		}
+		return nil, ErrNoAddrsAvail
+	}
+	if holder, ok := db.Addrs[AddrKey(addr, plen)]; ok {
+		// expired binding of another client
+		db.unpersist(holder)
+		db.release(holder)
+	}
+
+	b := &Binding{
+		Network:   s.Name,
+		DUID:      duid,
+		IAID:      iaid,
+		Addr:      addr,
+		PrefixLen: plen,
+		Valid:     s.Valid,
+		Preferred: s.Preferred,
+		State:     StateOffered,
+	}
+	db.Bindings[key] = b
+	db.Addrs[b.AddrKey()] = b
+	return b, nil
+}
+
+// reusable reports whether allocate gives the IA its existing binding again,
+// the caller must hold the lock
+func (db *LeaseDB) reusable(s *Scope, b *Binding, hint net.IP, plen int, fixed bool) bool {
+	_, reserved := s.Reserved[b.AddrKey()]
+	hinted := b.Addr.Equal(hint) && b.PrefixLen == plen
+	if b.Network != s.Name || !s.Assignable(b.Addr, b.PrefixLen) || (fixed && !hinted) || (!fixed && reserved) {
+		return false
+	}
+	holder := db.Addrs[b.AddrKey()]
+	return holder == b || holder == nil
+}
+
+// available reports whether the address or prefix is free or only held by an
+// expired binding
+func (db *LeaseDB) available(addrKey string, now time.Time) bool {
+	b, ok := db.Addrs[addrKey]
+	return !ok || b.Expired(now)
+}
+
+func (db *LeaseDB) release(b *Binding) {
+	if db.Bindings[b.Key()] == b {
+		delete(db.Bindings, b.Key())
+	}
+	if db.Addrs[b.AddrKey()] == b {
+		delete(db.Addrs, b.AddrKey())
+	}
+}
+
+// Holder returns the active binding of an address, nil if the address is free
+func (db *LeaseDB) Holder(ip net.IP) *Binding {
+	db.Locker.RLock()
+	defer db.Locker.RUnlock()
+	if b, ok := db.Addrs[AddrKey(ip, 0)]; ok && !b.Expired(time.Now()) {
+		return b
+	}
+	return nil
+}
+
+// CheckConflict returns ErrConflict when the address is actively bound to an
+// IA other than the given one
+func (db *LeaseDB) CheckConflict(ip net.IP, duid, iaid string) error {
+	if b := db.Holder(ip); b != nil && b.Key() != ClientKey(duid, iaid) {
+		return ErrConflict
+	}
+	return nil
+}
+
+// Find returns the binding of a client IA by ClientKey or PrefixKey, nil if none
+func (db *LeaseDB) Find(key string) *Binding {
+	db.Locker.RLock()
+	defer db.Locker.RUnlock()
+	return db.Bindings[key]
+}
+
+// Renew extends the binding of a client IA by ClientKey or PrefixKey.
+// ErrNotOnLink is returned when the binding no longer fits the scope
+func (db *LeaseDB) Renew(s *Scope, key string) (*Binding, error) {
+	db.Locker.Lock()
+	defer db.Locker.Unlock()
+	b, ok := db.Bindings[key]
+	if !ok || b.State != StateBound || db.Addrs[b.AddrKey()] != b {
+		return nil, ErrNoBinding
+	}
+	if s == nil || b.Network != s.Name || !s.Assignable(b.Addr, b.PrefixLen) {
+		return b, ErrNotOnLink
+	}
+	valid, preferred, expire := b.Valid, b.Preferred, b.Expire
+	b.Valid, b.Preferred = s.Valid, s.Preferred
+	b.Expire = time.Now().Add(s.Valid)
+	if err := db.persist(b); err != nil {
+		b.Valid, b.Preferred, b.Expire = valid, preferred, expire
+		return nil, err
+	}
+	return b, nil
+}
+
+// Release removes the binding of a client IA by ClientKey or PrefixKey
+func (db *LeaseDB) Release(key string) (*Binding, error) {
+	db.Locker.Lock()
+	defer db.Locker.Unlock()
+	b, ok := db.Bindings[key]
+	if !ok || b.State != StateBound {
+		return nil, ErrNoBinding
+	}
+	db.unpersist(b)
+	db.release(b)
+	return b, nil
+}
+
+// Decline removes the binding of a client IA_NA by ClientKey and holds its address out of
+// the pools for DECLINE_HOLD_TIME since it is in use by somebody else
+func (db *LeaseDB) Decline(key string, addr net.IP) (*Binding, error) {
+	db.Locker.Lock()
+	defer db.Locker.Unlock()
+	b, ok := db.Bindings[key]
+	if !ok || b.State != StateBound || !b.Addr.Equal(addr) {
+		return nil, ErrNoBinding
+	}
+	db.unpersist(b)
+	db.release(b)
+	declined := *b
+	declined.State = StateDeclined
+	declined.Expire = time.Now().Add(DECLINE_HOLD_TIME)
+	db.Addrs[declined.AddrKey()] = &declined
+	if err := db.persist(&declined); err != nil {
+		db.Log.WithFields(BindingFields(&declined)).Errorf("unable to save declined address: %v", err)
+	}
+	return &declined, nil
+}
+
+// OnLink reports whether the address is appropriate for the link of the scope
+func (s *Scope) OnLink(ip net.IP) bool {
+	if s.Prefix != nil {
+		return s.Prefix.Contains(ip)
+	}
+	return s.InPool(ip)
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/polarbroadband/rp1/proto/dhcp"
+)
+
+const (
+	testDUID  = "00030001020000000002"
+	otherDUID = "00030001020000000004"
+	testIA    = "00000001"
+)
+
+func testLog() *logrus.Entry {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	return logrus.NewEntry(l)
+}
+
+func testConfig6() *dhcp.Config {
+	return &dhcp.Config{Networks: map[string]*dhcp.Network{
+		"lan": {
+			Prefix:          "2001:db8::/64",
+			Pools:           []*dhcp.Pool{{Begin: "2001:db8::10", End: "2001:db8::12"}},
+			DelegatedLength: 80,
+			ValidLifetime:   3600,
+			Reservations:    []*dhcp.Reservation{{Name: "host", DUID: testDUID, Addr: "2001:db8::100"}},
+		},
+		"lan2": {
+			Prefix: "2001:db8:1::/64",
+			Pools:  []*dhcp.Pool{{Begin: "2001:db8:1::10", End: "2001:db8:1::20"}},
+		},
+	}}
+}
+
+func newTestLeaseDB(t *testing.T) *LeaseDB {
+	t.Helper()
+	db, err := NewLeaseDB(testConfig6(), testLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// held is a binding of the address in the lan network by another client, one
+// IA per address, expiring in an hour unless expired
+func held(addr string, state BindingState, expired bool) *Binding {
+	b := &Binding{Network: "lan", DUID: otherDUID, IAID: addr, Addr: net.ParseIP(addr).To16(), State: state, Expire: time.Now().Add(time.Hour)}
+	if expired {
+		b.Expire = time.Now().Add(-time.Second)
+	}
+	return b
+}
+
+func (db *LeaseDB) hold(b *Binding) {
+	if b.State != StateDeclined {
+		db.Bindings[b.Key()] = b
+	}
+	db.Addrs[b.AddrKey()] = b
+}
+
+func TestLeaseDBAllocate(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		held  []*Binding
+		hint  string
+		plen  int
+		fixed bool
+		want  string
+		err   error
+	}{
+		{"first free", nil, "", 0, false, "2001:db8::10", nil},
+		{"hint", nil, "2001:db8::11", 0, false, "2001:db8::11", nil},
+		{"hint out of pool", nil, "2001:db8::1", 0, false, "2001:db8::10", nil},
+		{"hint reserved", nil, "2001:db8::100", 0, false, "2001:db8::10", nil},
+		{"hint held", []*Binding{held("2001:db8::11", StateBound, false)}, "2001:db8::11", 0, false, "2001:db8::10", nil},
+		{"held skipped", []*Binding{held("2001:db8::10", StateBound, false), held("2001:db8::11", StateDeclined, false)}, "", 0, false, "2001:db8::12", nil},
+		{"expired reused", []*Binding{held("2001:db8::10", StateBound, true)}, "", 0, false, "2001:db8::10", nil},
+		{"exhausted", []*Binding{held("2001:db8::10", StateBound, false), held("2001:db8::11", StateOffered, false), held("2001:db8::12", StateDeclined, false)}, "", 0, false, "", ErrNoAddrsAvail},
+		{"fixed", nil, "2001:db8::100", 0, true, "2001:db8::100", nil},
+		{"fixed held", []*Binding{held("2001:db8::100", StateBound, false)}, "2001:db8::100", 0, true, "", ErrConflict},
+		{"prefix clear of the pools", nil, "", 80, false, "2001:db8:0:0:1::", nil},
+		{"prefix hint", nil, "2001:db8:0:0:5::", 80, false, "2001:db8:0:0:5::", nil},
+		{"prefix hint overlapping the pools", nil, "2001:db8::", 80, false, "2001:db8:0:0:1::", nil},
+		{"prefix length", nil, "", 96, false, "", ErrNoPrefixAvail},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newTestLeaseDB(t)
+			for _, b := range tc.held {
+				db.hold(b)
+			}
+			b, err := db.Offer(db.Scopes["lan"], testDUID, testIA, net.ParseIP(tc.hint), tc.plen, tc.fixed)
+			if err != tc.err {
+				t.Fatalf("error %v, want %v", err, tc.err)
+			}
+			if err != nil {
+				return
+			}
+			if want := AddrKey(net.ParseIP(tc.want), tc.plen); b.AddrKey() != want {
+				t.Errorf("offered %s, want %s", b.AddrKey(), want)
+			}
+			if b.State != StateOffered || b.Expired(time.Now()) {
+				t.Errorf("offer %v", b)
+			}
+			if db.Addrs[b.AddrKey()] != b || db.Bindings[b.Key()] != b {
+				t.Errorf("offer %v not indexed", b)
+			}
+		})
+	}
+}
+
+func TestLeaseDBSticky(t *testing.T) {
+	db := newTestLeaseDB(t)
+	s := db.Scopes["lan"]
+	offer, err := db.Offer(s, testDUID, testIA, nil, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bound, err := db.Commit(s, testDUID, testIA, net.ParseIP("2001:db8::12"), 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bound.Addr.Equal(offer.Addr) || bound.State != StateBound {
+		t.Errorf("committed %v, want %v bound", bound, offer.Addr)
+	}
+	if d := time.Until(bound.Expire); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("expires in %v, want the valid lifetime", d)
+	}
+	again, err := db.Offer(s, testDUID, testIA, nil, 0, false)
+	if err != nil || again != bound || again.State != StateBound {
+		t.Errorf("offer to the bound client %v %v, want %v", again, err, bound)
+	}
+	// the reservation takes over the dynamic binding
+	fixed, err := db.Commit(s, testDUID, testIA, net.ParseIP("2001:db8::100"), 0, true)
+	if err != nil || !fixed.Addr.Equal(net.ParseIP("2001:db8::100")) {
+		t.Fatalf("committed %v %v, want the reservation", fixed, err)
+	}
+	if db.Holder(bound.Addr) != nil {
+		t.Errorf("%v still held after moving to the reservation", bound.Addr)
+	}
+	// moving to another network gives up the address
+	moved, err := db.Commit(db.Scopes["lan2"], testDUID, testIA, nil, 0, false)
+	if err != nil || moved.Network != "lan2" {
+		t.Fatalf("committed %v %v, want a lan2 address", moved, err)
+	}
+	if db.Holder(fixed.Addr) != nil {
+		t.Errorf("%v still held after moving to lan2", fixed.Addr)
+	}
+}
+
+func TestLeaseDBBinding(t *testing.T) {
+	addr := net.ParseIP("2001:db8::10")
+	key := ClientKey(testDUID, testIA)
+	for _, tc := range []struct {
+		name string
+		op   func(db *LeaseDB) (*Binding, error)
+		err  error
+		// binding of the address afterwards, nil if free
+		holder *BindingState
+	}{
+		{"renew", func(db *LeaseDB) (*Binding, error) { return db.Renew(db.Scopes["lan"], key) }, nil, state(StateBound)},
+		{"renew unknown client", func(db *LeaseDB) (*Binding, error) {
+			return db.Renew(db.Scopes["lan"], ClientKey(otherDUID, testIA))
+		}, ErrNoBinding, state(StateBound)},
+		{"renew on another link", func(db *LeaseDB) (*Binding, error) { return db.Renew(db.Scopes["lan2"], key) }, ErrNotOnLink, state(StateBound)},
+		{"renew unknown link", func(db *LeaseDB) (*Binding, error) { return db.Renew(nil, key) }, ErrNotOnLink, state(StateBound)},
+		{"release", func(db *LeaseDB) (*Binding, error) { return db.Release(key) }, nil, nil},
+		{"release unknown client", func(db *LeaseDB) (*Binding, error) { return db.Release(ClientKey(otherDUID, testIA)) }, ErrNoBinding, state(StateBound)},
+		{"decline", func(db *LeaseDB) (*Binding, error) { return db.Decline(key, addr) }, nil, state(StateDeclined)},
+		{"decline another address", func(db *LeaseDB) (*Binding, error) {
+			return db.Decline(key, net.ParseIP("2001:db8::11"))
+		}, ErrNoBinding, state(StateBound)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newTestLeaseDB(t)
+			if _, err := db.Commit(db.Scopes["lan"], testDUID, testIA, addr, 0, false); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tc.op(db); err != tc.err {
+				t.Fatalf("error %v, want %v", err, tc.err)
+			}
+			h := db.Holder(addr)
+			if tc.holder == nil {
+				if h != nil {
+					t.Errorf("%v held by %v, want free", addr, h)
+				}
+				return
+			}
+			if h == nil || h.State != *tc.holder {
+				t.Fatalf("%v held by %v, want %v", addr, h, *tc.holder)
+			}
+			if h.State == StateDeclined {
+				if db.Find(key) != nil {
+					t.Errorf("declined binding still found")
+				}
+				// a declined address is kept from every client
+				if b, err := db.Offer(db.Scopes["lan"], otherDUID, testIA, addr, 0, false); err != nil || b.Addr.Equal(addr) {
+					t.Errorf("declined address offered: %v %v", b, err)
+				}
+			}
+		})
+	}
+}
+
+func state(s BindingState) *BindingState {
+	return &s
+}
+
+func TestSelectScope(t *testing.T) {
+	db, err := NewLeaseDB(&dhcp.Config{Networks: map[string]*dhcp.Network{
+		"lan":  {Prefix: "2001:db8::/64"},
+		"lan2": {Prefix: "2001:db8:1::/64", LinkAddr: "2001:db8:ff::1", InterfaceIDs: []string{"65746830"}},
+		"wide": {Prefix: "2001:db8::/48"},
+	}}, testLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name    string
+		relayed bool
+		link    string
+		ifid    string
+		want    string
+	}{
+		{"on the server link", false, "", "", "lan"},
+		{"longest prefix", true, "2001:db8::1", "", "lan"},
+		{"shorter prefix", true, "2001:db8:0:2::1", "", "wide"},
+		{"network link-address", true, "2001:db8:ff::1", "", "lan2"},
+		{"interface-id", true, "", "eth0", "lan2"},
+		{"link-local link-address", true, "fe80::1", "eth0", "lan2"},
+		{"link-address over interface-id", true, "2001:db8::1", "eth0", "lan"},
+		{"unknown link", true, "2001:db9::1", "eth9", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var ifid []byte
+			if tc.ifid != "" {
+				ifid = []byte(tc.ifid)
+			}
+			got := ""
+			if s := db.SelectScope(false, tc.relayed, net.ParseIP(tc.link), ifid); s != nil {
+				got = s.Name
+			}
+			if got != tc.want {
+				t.Errorf("selected %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
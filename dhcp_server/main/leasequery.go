@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/iana"
+)
+
+// RFC 5007 section 4.1.2.1 query types
+const (
+	LQ_QUERY_BY_ADDRESS  = 1
+	LQ_QUERY_BY_CLIENTID = 2
+)
+
+// LeaseFilter selects bindings, zero fields match any
+type LeaseFilter struct {
+	Addr         *net.IPNet // bindings of addresses within, or prefixes overlapping
+	DUID         string
+	IAID         string
+	Network      string
+	State        string
+	ExpireAfter  time.Time
+	ExpireBefore time.Time
+	IPv4, IPv6   bool // family, both if none set
+}
+
+func (f *LeaseFilter) Match(b *Binding) bool {
+	if f.Addr != nil && !f.Addr.Contains(b.Addr) && (b.Prefix() == nil || !b.Prefix().Contains(f.Addr.IP)) {
+		return false
+	}
+	if f.DUID != "" && f.DUID != b.DUID {
+		return false
+	}
+	if f.IAID != "" && f.IAID != b.IAID {
+		return false
+	}
+	if f.Network != "" && f.Network != b.Network {
+		return false
+	}
+	if f.State != "" && f.State != b.State.String() {
+		return false
+	}
+	if !f.ExpireAfter.IsZero() && !b.Expire.After(f.ExpireAfter) {
+		return false
+	}
+	if !f.ExpireBefore.IsZero() && !b.Expire.Before(f.ExpireBefore) {
+		return false
+	}
+	if f.IPv4 != f.IPv6 && f.IPv4 != (b.Addr.To4() != nil) {
+		return false
+	}
+	return true
+}
+
+// Search returns copies of the unexpired bindings matching the filter, by
+// address
+func (db *LeaseDB) Search(f LeaseFilter) []*Binding {
+	db.Locker.RLock()
+	defer db.Locker.RUnlock()
+	now := time.Now()
+	found := []*Binding{}
+	for _, b := range db.Addrs {
+		if !b.Expired(now) && f.Match(b) {
+			c := *b
+			found = append(found, &c)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if c := bytes.Compare(found[i].Addr.To16(), found[j].Addr.To16()); c != 0 {
+			return c < 0
+		}
+		return found[i].PrefixLen < found[j].PrefixLen
+	})
+	return found
+}
+
+// LinkAddr returns the address identifying the link of the scope
+func (s *Scope) LinkAddr() net.IP {
+	if ip := net.ParseIP(s.Network.GetLinkAddr()); ip != nil {
+		return ip
+	}
+	if s.Prefix != nil {
+		return s.Prefix.IP
+	}
+	return net.IPv6unspecified
+}
+
+// LeaseQuery answers a requestor asking which client holds an address or
+// which bindings a client holds, as RFC 5007 section 4.3.3 defines it
+func (s *DhcpServer) LeaseQuery(t *Txn) (*dhcpv6.Message, error) {
+	msg := t.Msg
+	resp := &dhcpv6.Message{
+		MessageType:   dhcpv6.MessageTypeLeaseQueryReply,
+		TransactionID: msg.TransactionID,
+	}
+	resp.AddOption(dhcpv6.OptServerID(s.DUID))
+	if cid := msg.Options.ClientID(); cid != nil {
+		resp.AddOption(dhcpv6.OptClientID(*cid))
+	}
+	fail := func(code iana.StatusCode, text string) (*dhcpv6.Message, error) {
+		t.Log.Warnf("leasequery refused: %s", text)
+		resp.AddOption(&dhcpv6.OptStatusCode{StatusCode: code, StatusMessage: text})
+		return resp, nil
+	}
+
+	if !s.LeaseQueryAllowed(t.Peer) {
+		return fail(iana.StatusNotAllowed, "requestor not allowed")
+	}
+	q := msg.GetOneOption(dhcpv6.OptionLQQuery)
+	if q == nil || msg.Options.ClientID() == nil {
+		return fail(iana.StatusMalformedQuery, "query or client identifier missing")
+	}
+	data := q.ToBytes()
+	if len(data) < 1+net.IPv6len {
+		return fail(iana.StatusMalformedQuery, "query too short")
+	}
+	qtype, link := data[0], net.IP(data[1:1+net.IPv6len])
+	qopts := dhcpv6.Options{}
+	if err := qopts.FromBytes(data[1+net.IPv6len:]); err != nil {
+		return fail(iana.StatusMalformedQuery, fmt.Sprintf("invalid query options %v", err))
+	}
+
+	f := LeaseFilter{IPv6: true, State: StateBound.String()}
+	if !link.IsUnspecified() {
+		scope := s.Leases.SelectScope(false, true, link, nil)
+		if scope == nil {
+			return fail(iana.StatusNotConfigured, fmt.Sprintf("link %v not configured", link))
+		}
+		f.Network = scope.Name
+	}
+	switch qtype {
+	case LQ_QUERY_BY_ADDRESS:
+		ia, ok := qopts.GetOne(dhcpv6.OptionIAAddr).(*dhcpv6.OptIAAddress)
+		if !ok {
+			return fail(iana.StatusMalformedQuery, "address missing")
+		}
+		f.Addr = &net.IPNet{IP: ia.IPv6Addr, Mask: net.CIDRMask(128, 128)}
+		holders := s.Leases.Search(f)
+		if len(holders) == 0 {
+			t.Log.WithField("query", ia.IPv6Addr.String()).Info("leasequery, no binding")
+			return resp, nil
+		}
+		// every binding of the holder on the link of the address
+		f.Addr, f.DUID, f.Network = nil, holders[0].DUID, holders[0].Network
+	case LQ_QUERY_BY_CLIENTID:
+		cid := qopts.GetOne(dhcpv6.OptionClientID)
+		if cid == nil {
+			return fail(iana.StatusMalformedQuery, "client identifier missing")
+		}
+		f.DUID = hex.EncodeToString(cid.ToBytes())
+	default:
+		return fail(iana.StatusUnknownQueryType, fmt.Sprintf("unknown query type %v", qtype))
+	}
+
+	bindings := s.Leases.Search(f)
+	networks := map[string]bool{}
+	for _, b := range bindings {
+		networks[b.Network] = true
+	}
+	log := t.Log.WithField("query", f.DUID)
+	if len(networks) > 1 {
+		// the requestor has to pick the link and query again
+		links := []byte{}
+		s.Leases.Locker.RLock()
+		for name := range networks {
+			if scope := s.Leases.Scopes[name]; scope != nil {
+				links = append(links, scope.LinkAddr().To16()...)
+			}
+		}
+		s.Leases.Locker.RUnlock()
+		resp.AddOption(&dhcpv6.OptionGeneric{OptionCode: dhcpv6.OptionLQClientLink, OptionData: links})
+		log.Infof("leasequery, client on %v links", len(networks))
+		return resp, nil
+	}
+	if len(bindings) > 0 {
+		resp.AddOption(ClientData(bindings))
+	}
+	log.Infof("leasequery, %v bindings", len(bindings))
+	return resp, nil
+}
+
+// ClientData builds the RFC 5007 client data option out of the bindings of
+// one client, the last transaction time is when the bindings were last
+// extended
+func ClientData(bindings []*Binding) dhcpv6.Option {
+	opts := dhcpv6.Options{}
+	if duid, err := hex.DecodeString(bindings[0].DUID); err == nil {
+		opts.Add(&dhcpv6.OptionGeneric{OptionCode: dhcpv6.OptionClientID, OptionData: duid})
+	}
+	now := time.Now()
+	clt := time.Duration(0)
+	for _, b := range bindings {
+		valid, preferred := time.Until(b.Expire), b.Preferred-(b.Valid-time.Until(b.Expire))
+		if preferred < 0 {
+			preferred = 0
+		}
+		if p := b.Prefix(); p != nil {
+			opts.Add(&dhcpv6.OptIAPrefix{Prefix: p, ValidLifetime: valid, PreferredLifetime: preferred})
+		} else {
+			opts.Add(&dhcpv6.OptIAAddress{IPv6Addr: b.Addr, ValidLifetime: valid, PreferredLifetime: preferred})
+		}
+		if since := now.Sub(b.Expire.Add(-b.Valid)); clt == 0 || since < clt {
+			clt = since
+		}
+	}
+	sec := uint32(clt / time.Second)
+	opts.Add(&dhcpv6.OptionGeneric{OptionCode: dhcpv6.OptionCLTTime, OptionData: []byte{byte(sec >> 24), byte(sec >> 16), byte(sec >> 8), byte(sec)}})
+	return &dhcpv6.OptionGeneric{OptionCode: dhcpv6.OptionClientData, OptionData: opts.ToBytes()}
+}
+
+// LeaseQueryAllowed reports whether the requestor may query the bindings,
+// nobody when no network is configured
+func (s *DhcpServer) LeaseQueryAllowed(ip net.IP) bool {
+	for _, n := range s.LeaseQueryFrom {
+		if ip != nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseNets reads a comma separated list of networks, addresses are taken as
+// host networks
+func ParseNets(s string) ([]*net.IPNet, error) {
+	nets := []*net.IPNet{}
+	for _, n := range strings.Split(s, ",") {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
+		}
+		if ip := net.ParseIP(n); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipnet, err := net.ParseCIDR(n)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %s", n)
+		}
+		nets = append(nets, ipnet)
+	}
+	return nets, nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+func TestLeaseQueryAllowed(t *testing.T) {
+	_, relays, _ := net.ParseCIDR("2001:db8:ff::/48")
+	for _, tc := range []struct {
+		name  string
+		from  []*net.IPNet
+		peer  string
+		allow bool
+	}{
+		{"no requestor network", nil, "2001:db8:ff::1", false},
+		{"requestor network", []*net.IPNet{relays}, "2001:db8:ff::1", true},
+		{"other network", []*net.IPNet{relays}, "2001:db8:1::1", false},
+		{"no peer", []*net.IPNet{relays}, "", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &DhcpServer{LeaseQueryFrom: tc.from}
+			if got := s.LeaseQueryAllowed(net.ParseIP(tc.peer)); got != tc.allow {
+				t.Errorf("allowed %v, want %v", got, tc.allow)
+			}
+		})
+	}
+}
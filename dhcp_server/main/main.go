@@ -1,81 +1,279 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
-	uuid "github.com/google/UUID"
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv4/server4"
 	"github.com/insomniacslk/dhcp/dhcpv6"
 	"github.com/insomniacslk/dhcp/dhcpv6/server6"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/polarbroadband/rp1/etcdlib"
+	"github.com/polarbroadband/rp1/proto/dhcp"
+	etcd "go.etcd.io/etcd/client/v3"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
 )
 
+func init() {
+	logrus.SetFormatter(&logrus.JSONFormatter{PrettyPrint: true})
+	logrus.SetOutput(os.Stdout)
+	logrus.SetLevel(logrus.TraceLevel)
+	logrus.SetReportCaller(true)
+}
+
 var (
-	//SVR_UUID = os.Getenv("SVR_UUID")
-	SVR_UUID = "b70ee641-c51f-4ed6-b34b-e060dfceff46"
-	DUID     dhcpv6.Duid
-)
+	// server DUID, a UUID or the hex encoded DUID, generated and saved in
+	// etcd when empty
+	SVR_UUID = os.Getenv("SVR_UUID")
+	// type of the generated DUID, UUID, LLT or EN
+	SVR_DUID_TYPE = os.Getenv("SVR_DUID_TYPE")
+	// enterprise number of a generated DUID-EN
+	SVR_DUID_EN = os.Getenv("SVR_DUID_EN")
 
-func handler(conn net.PacketConn, peer net.Addr, r dhcpv6.DHCPv6) {
-	log.Print(r.Summary())
-	clntMsg, err := r.GetInnerMessage()
-	if err != nil {
-		log.Fatal(err)
-	}
+	// interfaces to listen on, comma separated
+	SVR_INTERFACES = strings.Split(os.Getenv("SVR_INTERFACES"), ",")
 
-	var resp dhcpv6.DHCPv6
-	switch clntMsg.Type() {
+	// seconds a leader replica keeps serving clients after losing etcd
+	SVR_ELECTION_TTL = os.Getenv("SVR_ELECTION_TTL")
 
-	// SOLICIT
-	case dhcpv6.MessageTypeSolicit:
-		if resp, err = dhcpv6.NewAdvertiseFromSolicit(clntMsg, dhcpv6.WithServerID(DUID), dhcpv6.WithIANA()); err != nil {
-			log.Fatal(err)
-		}
-		log.Print(resp.Summary())
+	// networks or addresses of the LEASEQUERY requestors, comma separated,
+	// LEASEQUERY is refused if empty
+	LEASEQUERY_FROM = os.Getenv("LEASEQUERY_FROM")
 
-	// REQUEST
-	case dhcpv6.MessageTypeRequest:
-		ops := dhcpv6.Options{}
-		ops.Add(dhcpv6.OptBootFileURL("1.2.3.4/file/boot"))
-		if resp, err = dhcpv6.NewReplyFromMessage(clntMsg, dhcpv6.WithIANA(dhcpv6.OptIAAddress{IPv6Addr: net.ParseIP("ABCD::FFFF"), ValidLifetime: time.Second * 1000, Options: dhcpv6.AddressOptions{ops}})); err != nil {
-			log.Fatal(err)
-		}
-		log.Print(resp.Summary())
+	// listen address of the lease listing, see ParseLeaseFilter, disabled if
+	// empty. It is not authenticated, keep it off the metrics port
+	LEASES_ADDR = os.Getenv("LEASES_ADDR")
+
+	ETCD_IaC_DHCP  = os.Getenv("ETCD_IaC_DHCP") // "/cirrus/iac/dhcp"
+	ETCD_ENDPOINTS = strings.Split(os.Getenv("ETCD_ENDPOINTS"), ",")
+	ETCD_USERNAME  = os.Getenv("ETCD_USERNAME")
+	ETCD_PASSWORD  = os.Getenv("ETCD_PASSWORD")
+)
 
+func main() {
+	hostname, err := os.Hostname()
+	if err != nil {
+		logrus.Fatal(err)
 	}
-	if r.IsRelay() {
+	log := logrus.WithFields(logrus.Fields{"wkr": hostname, "pkg": "dhcp_server"})
 
-		rr, err := dhcpv6.NewRelayReplFromRelayForw(r.(*dhcpv6.RelayMessage), resp.(*dhcpv6.Message))
-		if err != nil {
-			log.Fatal(err)
+	if SVR_UUID != "" && SVR_DUID_TYPE == "" {
+		SVR_DUID_TYPE = "UUID"
+	}
+	enterprise, err := strconv.ParseUint("0"+SVR_DUID_EN, 10, 32)
+	if err != nil {
+		log.Fatalf("invalid env variable SVR_DUID_EN: %v", err)
+	}
+	if SVR_INTERFACES[0] == "" {
+		SVR_INTERFACES = []string{"eth1"}
+	}
+	port6, err := EnvPort("SVR_PORT_V6", dhcpv6.DefaultServerPort)
+	if err != nil {
+		log.Fatal(err)
+	}
+	port4, err := EnvPort("SVR_PORT_V4", dhcpv4.ServerPort)
+	if err != nil {
+		log.Fatal(err)
+	}
+	metricsPort, err := EnvPort("METRICS_PORT", 2112)
+	if err != nil {
+		log.Fatal(err)
+	}
+	electionTTL := DEFAULT_ELECTION_TTL
+	if SVR_ELECTION_TTL != "" {
+		if electionTTL, err = strconv.Atoi(SVR_ELECTION_TTL); err != nil || electionTTL < 1 {
+			log.Fatalf("invalid env variable SVR_ELECTION_TTL: %s", SVR_ELECTION_TTL)
 		}
-		resp = rr
 	}
-	if _, err := conn.WriteTo(resp.ToBytes(), peer); err != nil {
-		log.Printf("failed to send resp: %v", err)
+
+	etcdClientCfg := etcd.Config{
+		Endpoints:   ETCD_ENDPOINTS,
+		Username:    ETCD_USERNAME,
+		Password:    ETCD_PASSWORD,
+		DialTimeout: etcdlib.DEFAULT_ETCD_DIAL_TIMEOUT,
+	}
+	if dialTimeout, err := time.ParseDuration(os.Getenv("ETCD_DIAL_TIMEOUT")); err != nil {
+		log.Warnf("invalid env variable ETCD_DIAL_TIMEOUT: %v, set to %v", err, etcdClientCfg.DialTimeout)
 	} else {
-		log.Print("response sent")
+		etcdClientCfg.DialTimeout = dialTimeout
 	}
-}
+	etcdClient, err := etcd.New(etcdClientCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer etcdClient.Close()
 
-func main() {
+	// networks published by gitops
+	netDepot := etcdlib.NewKvDepot(ETCD_IaC_DHCP, etcdClient, log)
+	current, netCH, err := netDepot.Subscribe("network")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer netDepot.Cancel()
 
-	if uuid, err := uuid.Parse(SVR_UUID); err != nil {
+	cfg := &dhcp.Config{}
+	if current == nil {
+		log.Warn("network data not available, no network configured")
+	} else if err := proto.Unmarshal(current.Value, cfg); err != nil {
+		log.Errorf("invalid network data %v, no network configured", err)
+		cfg = &dhcp.Config{}
+	}
+	leases, err := NewLeaseDB(&dhcp.Config{}, log)
+	if err != nil {
 		log.Fatal(err)
-	} else if id, err := uuid.MarshalBinary(); err != nil {
+	}
+
+	// bindings are shared by every server replica through the lease directory
+	leases.Depot = etcdlib.NewKvDepot(ETCD_IaC_DHCP, etcdClient, log)
+	currentLeases, leaseCH, err := leases.Depot.SubscribeDir(LEASE_DIR)
+	if err != nil {
 		log.Fatal(err)
+	}
+	defer leases.Depot.Cancel()
+	leases.Restore(currentLeases)
+	// the networks are checked against the restored bindings, a config the
+	// running replicas refused is refused here too and waits for the next one
+	if err := leases.Update(cfg); err != nil {
+		log.Errorf("refused networks of commit %s: %v, no network configured", cfg.GetCommit(), err)
 	} else {
-		DUID = dhcpv6.Duid{Type: dhcpv6.DUID_UUID, Uuid: id}
+		log.Infof("networks of commit %s loaded", cfg.GetCommit())
 	}
+	go leases.Mirror(leaseCH)
 
-	laddr := net.UDPAddr{
-		IP:   net.ParseIP("::"),
-		Port: dhcpv6.DefaultServerPort,
-	}
-	server, err := server6.NewServer("eth1", &laddr, handler, server6.WithSummaryLogger())
+	// replicas share the bindings, the elected one serves the clients and
+	// hands over on shutdown
+	leases.Election = NewElection(leases.Depot, hostname, electionTTL)
+	go leases.Election.Run(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		leases.Election.Resign()
+		log.Info("stopped")
+		os.Exit(0)
+	}()
+
+	duid, err := ServerDuid(netDepot, SVR_DUID_TYPE, SVR_UUID, SVR_INTERFACES[0], uint32(enterprise))
 	if err != nil {
 		log.Fatal(err)
 	}
+	log.Infof("server %s %x", duid.Type, duid.ToBytes())
 
-	server.Serve()
+	lqFrom, err := ParseNets(LEASEQUERY_FROM)
+	if err != nil {
+		log.Fatalf("invalid env variable LEASEQUERY_FROM: %v", err)
+	}
+
+	reg := prometheus.NewRegistry()
+	svr := DhcpServer{DUID: *duid, Leases: leases, Metrics: NewMetrics(reg, leases), Log: log, LeaseQueryFrom: lqFrom}
+
+	go func() {
+		log.Infof("start network watcher %s/network", ETCD_IaC_DHCP)
+		for wresp := range netCH {
+			if err := wresp.Err(); err != nil {
+				log.Errorf("network watch failed: %v", err)
+				continue
+			}
+			if len(wresp.Events) == 0 {
+				continue
+			}
+			ev := wresp.Events[len(wresp.Events)-1]
+			if ev.Type == etcd.EventTypeDelete {
+				log.Warn("network data deleted, networks kept")
+				continue
+			}
+			cfg := &dhcp.Config{}
+			if err := proto.Unmarshal(ev.Kv.Value, cfg); err != nil {
+				log.Errorf("received invalid network data %v", err)
+				continue
+			}
+			changed := leases.ChangedOptions(cfg)
+			if err := leases.Update(cfg); err != nil {
+				log.Errorf("refused networks of commit %s: %v", cfg.GetCommit(), err)
+				continue
+			}
+			log.Infof("networks of commit %s applied", cfg.GetCommit())
+			// clients holding a Reconfigure key pick up the new options
+			go svr.Reconfigure(changed)
+		}
+		log.Warn("network watcher stopped")
+	}()
+
+	if metricsPort > 0 {
+		go func() {
+			http.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg}))
+			log.Fatal(http.ListenAndServe(fmt.Sprintf(":%v", metricsPort), nil))
+		}()
+	}
+	if LEASES_ADDR != "" {
+		go func() {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/leases", leases.LeaseHandler)
+			log.Infof("start lease listing %s", LEASES_ADDR)
+			log.Fatal(http.ListenAndServe(LEASES_ADDR, mux))
+		}()
+	}
+
+	// every interface serves both families, a listener failure stops the server
+	errCH := make(chan error)
+	listeners := 0
+	for _, ifname := range SVR_INTERFACES {
+		iface, err := net.InterfaceByName(ifname)
+		if err != nil {
+			log.Fatal(err)
+		}
+		svr6 := svr
+		svr6.Log = log.WithField("iface", ifname)
+		if port6 > 0 {
+			laddr := net.UDPAddr{
+				IP:   net.IPv6unspecified,
+				Port: port6,
+			}
+			server, err := server6.NewServer(ifname, &laddr, svr6.Handler, server6.WithSummaryLogger())
+			if err != nil {
+				log.Fatal(err)
+			}
+			go func() { errCH <- server.Serve() }()
+			listeners++
+		}
+
+		// DHCPv4 is served when the interface has an address to identify the server
+		if port4 == 0 {
+			continue
+		}
+		addrs, err := dhcpv4.IPv4AddrsForInterface(iface)
+		if err != nil || len(addrs) == 0 {
+			log.Warnf("no IPv4 address on %s, DHCPv4 disabled", ifname)
+			continue
+		}
+		svr4 := svr6
+		svr4.ServerIP = addrs[0]
+		laddr4 := net.UDPAddr{
+			IP:   net.IPv4zero,
+			Port: port4,
+		}
+		server, err := server4.NewServer(ifname, &laddr4, svr4.Handler4, server4.WithSummaryLogger())
+		if err != nil {
+			log.Fatal(err)
+		}
+		go func() { errCH <- server.Serve() }()
+		listeners++
+	}
+	if listeners == 0 {
+		log.Fatal("no DHCP listener enabled")
+	}
+	log.Fatal(<-errCH)
 }
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type Metrics struct {
+	Request  *prometheus.CounterVec
+	Response *prometheus.CounterVec
+	Failure  *prometheus.CounterVec
+	Decline  *prometheus.CounterVec
+	Conflict *prometheus.CounterVec
+}
+
+func NewMetrics(reg prometheus.Registerer, db *LeaseDB) *Metrics {
+	m := &Metrics{
+		Request: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "dhcp_requests_total",
+				Help: "Number of received DHCP client messages",
+			},
+			[]string{"family", "type"},
+		),
+		Response: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "dhcp_responses_total",
+				Help: "Number of DHCP responses sent, or messages discarded",
+			},
+			[]string{"family", "type"},
+		),
+		Failure: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "dhcp_ia_failures_total",
+				Help: "Number of addresses and prefixes refused to clients, by status",
+			},
+			[]string{"family", "status"},
+		),
+		Decline: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "dhcp_declines_total",
+				Help: "Number of addresses declined by clients",
+			},
+			[]string{"family"},
+		),
+		Conflict: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "dhcp_conflicts_total",
+				Help: "Number of clients asking for addresses bound to other clients",
+			},
+			[]string{"family"},
+		),
+	}
+	reg.MustRegister(m.Request)
+	reg.MustRegister(m.Response)
+	reg.MustRegister(m.Failure)
+	reg.MustRegister(m.Decline)
+	reg.MustRegister(m.Conflict)
+	reg.MustRegister(&leaseCollector{db})
+	reg.MustRegister(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "dhcp_leader",
+			Help: "Whether the replica serves the clients, 0 when standing by",
+		},
+		func() float64 {
+			if db.Election != nil && !db.Election.Leading() {
+				return 0
+			}
+			return 1
+		},
+	))
+	return m
+}
+
+var (
+	poolSizeDesc = prometheus.NewDesc(
+		"dhcp_pool_size",
+		"Number of addresses of the pool",
+		[]string{"network", "pool"}, nil,
+	)
+	poolUsedDesc = prometheus.NewDesc(
+		"dhcp_pool_used",
+		"Number of unexpired addresses of the pool, by binding state",
+		[]string{"network", "pool", "state"}, nil,
+	)
+	poolUtilizationDesc = prometheus.NewDesc(
+		"dhcp_pool_utilization_ratio",
+		"Ratio of the addresses of the pool unavailable to new clients",
+		[]string{"network", "pool"}, nil,
+	)
+	activeLeasesDesc = prometheus.NewDesc(
+		"dhcp_active_leases",
+		"Number of unexpired bound leases of the network, by kind",
+		[]string{"network", "kind"}, nil,
+	)
+)
+
+// leaseCollector reports the pool and lease gauges out of the bindings at
+// scrape time, bindings of every server replica are mirrored in
+type leaseCollector struct {
+	db *LeaseDB
+}
+
+func (c *leaseCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolSizeDesc
+	ch <- poolUsedDesc
+	ch <- poolUtilizationDesc
+	ch <- activeLeasesDesc
+}
+
+func (c *leaseCollector) Collect(ch chan<- prometheus.Metric) {
+	c.db.Locker.RLock()
+	defer c.db.Locker.RUnlock()
+	now := time.Now()
+	for name, s := range c.db.Scopes {
+		active := map[string]int{"address": 0, "prefix": 0}
+		for _, r := range s.Pools {
+			pool := r.Begin.String() + "-" + r.End.String()
+			size := r.Size()
+			used := map[BindingState]int{StateOffered: 0, StateBound: 0, StateDeclined: 0}
+			for _, b := range c.db.Addrs {
+				if b.PrefixLen == 0 && b.Network == name && !b.Expired(now) && r.Contains(b.Addr) {
+					used[b.State]++
+				}
+			}
+			total := 0
+			for state, n := range used {
+				total += n
+				ch <- prometheus.MustNewConstMetric(poolUsedDesc, prometheus.GaugeValue, float64(n), name, pool, state.String())
+			}
+			ch <- prometheus.MustNewConstMetric(poolSizeDesc, prometheus.GaugeValue, size, name, pool)
+			ch <- prometheus.MustNewConstMetric(poolUtilizationDesc, prometheus.GaugeValue, float64(total)/size, name, pool)
+		}
+		for _, b := range c.db.Bindings {
+			if b.Network != name || b.State != StateBound || b.Expired(now) {
+				continue
+			}
+			if b.PrefixLen > 0 {
+				active["prefix"]++
+			} else {
+				active["address"]++
+			}
+		}
+		for kind, n := range active {
+			ch <- prometheus.MustNewConstMetric(activeLeasesDesc, prometheus.GaugeValue, float64(n), name, kind)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/hex"
+	"net"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/rfc1035label"
+	"github.com/polarbroadband/rp1/proto/dhcp"
+)
+
+// MergeOptions overlays the options of a reservation on the ones of its
+// network, each field set in over replaces the one of base, raw and vendor
+// options are replaced by code and enterprise number
+func MergeOptions(base, over *dhcp.Options) *dhcp.Options {
+	if over == nil {
+		return base
+	}
+	if base == nil {
+		return over
+	}
+	o := &dhcp.Options{
+		DNSServers:     base.GetDNSServers(),
+		DomainList:     base.GetDomainList(),
+		BootFileURL:    base.GetBootFileURL(),
+		BootFileParams: base.GetBootFileParams(),
+		NTPServers:     base.GetNTPServers(),
+	}
+	if len(over.GetDNSServers()) > 0 {
+		o.DNSServers = over.GetDNSServers()
+	}
+	if len(over.GetDomainList()) > 0 {
+		o.DomainList = over.GetDomainList()
+	}
+	if over.GetBootFileURL() != "" {
+		o.BootFileURL = over.GetBootFileURL()
+		o.BootFileParams = over.GetBootFileParams()
+	}
+	if len(over.GetNTPServers()) > 0 {
+		o.NTPServers = over.GetNTPServers()
+	}
+	for _, v := range base.GetVendorOptions() {
+		replaced := false
+		for _, ov := range over.GetVendorOptions() {
+			replaced = replaced || ov.GetEnterpriseNumber() == v.GetEnterpriseNumber()
+		}
+		if !replaced {
+			o.VendorOptions = append(o.VendorOptions, v)
+		}
+	}
+	o.VendorOptions = append(o.VendorOptions, over.GetVendorOptions()...)
+	for _, r := range base.GetRawOptions() {
+		replaced := false
+		for _, or := range over.GetRawOptions() {
+			replaced = replaced || or.GetCode() == r.GetCode()
+		}
+		if !replaced {
+			o.RawOptions = append(o.RawOptions, r)
+		}
+	}
+	o.RawOptions = append(o.RawOptions, over.GetRawOptions()...)
+	return o
+}
+
+// EncodeOptions converts an option set into DHCPv6 options, the model is
+// expected to be validated already so malformed entries are skipped
+func EncodeOptions(o *dhcp.Options) dhcpv6.Options {
+	opts := dhcpv6.Options{}
+	if o == nil {
+		return opts
+	}
+	if ips := parseIPs(o.GetDNSServers()); len(ips) > 0 {
+		opts.Add(dhcpv6.OptDNS(ips...))
+	}
+	if len(o.GetDomainList()) > 0 {
+		opts.Add(dhcpv6.OptDomainSearchList(&rfc1035label.Labels{Labels: o.GetDomainList()}))
+	}
+	if o.GetBootFileURL() != "" {
+		opts.Add(dhcpv6.OptBootFileURL(o.GetBootFileURL()))
+		if len(o.GetBootFileParams()) > 0 {
+			opts.Add(dhcpv6.OptBootFileParam(o.GetBootFileParams()...))
+		}
+	}
+	if ips := parseIPs(o.GetNTPServers()); len(ips) > 0 {
+		ntp := &dhcpv6.OptNTPServer{}
+		for _, ip := range ips {
+			addr := dhcpv6.NTPSuboptionSrvAddr(ip)
+			ntp.Suboptions.Add(&addr)
+		}
+		opts.Add(ntp)
+	}
+	for _, v := range o.GetVendorOptions() {
+		vo := &dhcpv6.OptVendorOpts{EnterpriseNumber: v.GetEnterpriseNumber()}
+		for _, r := range v.GetOptions() {
+			if opt := rawOption(r); opt != nil {
+				vo.VendorOpts.Add(opt)
+			}
+		}
+		opts.Add(vo)
+	}
+	for _, r := range o.GetRawOptions() {
+		if opt := rawOption(r); opt != nil {
+			opts.Add(opt)
+		}
+	}
+	return opts
+}
+
+// AddRequestedOptions adds to the response the options of the set the client
+// asked for in its Option Request Option
+func AddRequestedOptions(msg, resp *dhcpv6.Message, o *dhcp.Options) {
+	oro := msg.Options.RequestedOptions()
+	for _, opt := range EncodeOptions(o) {
+		if oro.Contains(opt.Code()) {
+			resp.AddOption(opt)
+		}
+	}
+}
+
+// EncodeOptions4 converts an option set into DHCPv4 options, the boot file
+// URL goes into the boot file name and vendor options into the RFC 3925
+// vendor-identifying vendor-specific option
+func EncodeOptions4(o *dhcp.Options) []dhcpv4.Option {
+	opts := []dhcpv4.Option{}
+	if o == nil {
+		return opts
+	}
+	if ips := parseIPs4(o.GetDNSServers()); len(ips) > 0 {
+		opts = append(opts, dhcpv4.OptDNS(ips...))
+	}
+	if len(o.GetDomainList()) > 0 {
+		opts = append(opts,
+			dhcpv4.OptDomainName(o.GetDomainList()[0]),
+			dhcpv4.OptDomainSearch(&rfc1035label.Labels{Labels: o.GetDomainList()}),
+		)
+	}
+	if o.GetBootFileURL() != "" {
+		opts = append(opts, dhcpv4.OptBootFileName(o.GetBootFileURL()))
+	}
+	if ips := parseIPs4(o.GetNTPServers()); len(ips) > 0 {
+		opts = append(opts, dhcpv4.OptNTPServers(ips...))
+	}
+	if len(o.GetVendorOptions()) > 0 {
+		data := []byte{}
+		for _, v := range o.GetVendorOptions() {
+			sub := []byte{}
+			for _, r := range v.GetOptions() {
+				if b, err := hex.DecodeString(r.GetData()); err == nil && r.GetCode() <= 0xff && len(b) <= 0xff {
+					sub = append(sub, byte(r.GetCode()), byte(len(b)))
+					sub = append(sub, b...)
+				}
+			}
+			en := v.GetEnterpriseNumber()
+			data = append(data, byte(en>>24), byte(en>>16), byte(en>>8), byte(en), byte(len(sub)))
+			data = append(data, sub...)
+		}
+		opts = append(opts, dhcpv4.OptGeneric(dhcpv4.OptionVendorIdentifyingVendorSpecific, data))
+	}
+	for _, r := range o.GetRawOptions() {
+		if b, err := hex.DecodeString(r.GetData()); err == nil && r.GetCode() > 0 && r.GetCode() < 0xff {
+			opts = append(opts, dhcpv4.OptGeneric(dhcpv4.GenericOptionCode(r.GetCode()), b))
+		}
+	}
+	return opts
+}
+
+// AddRequestedOptions4 adds to the response the options of the set the client
+// asked for in its Parameter Request List, all of them without the list
+func AddRequestedOptions4(req, resp *dhcpv4.DHCPv4, o *dhcp.Options) {
+	for _, opt := range EncodeOptions4(o) {
+		if Requested4(req, opt.Code) {
+			resp.UpdateOption(opt)
+		}
+	}
+}
+
+// Requested4 reports whether the DHCPv4 client asked for the option, as
+// option codes of different types are not comparable only the values are
+func Requested4(req *dhcpv4.DHCPv4, code dhcpv4.OptionCode) bool {
+	prl := req.ParameterRequestList()
+	if prl == nil {
+		return true
+	}
+	for _, c := range prl {
+		if c.Code() == code.Code() {
+			return true
+		}
+	}
+	return false
+}
+
+func rawOption(r *dhcp.RawOption) dhcpv6.Option {
+	data, err := hex.DecodeString(r.GetData())
+	if err != nil || r.GetCode() == 0 || r.GetCode() > 0xffff {
+		return nil
+	}
+	return &dhcpv6.OptionGeneric{OptionCode: dhcpv6.OptionCode(r.GetCode()), OptionData: data}
+}
+
+func parseIPs4(addrs []string) []net.IP {
+	ips := []net.IP{}
+	for _, a := range addrs {
+		if ip := net.ParseIP(a).To4(); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+func parseIPs(addrs []string) []net.IP {
+	ips := []net.IP{}
+	for _, a := range addrs {
+		if ip := net.ParseIP(a); ip != nil && ip.To4() == nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
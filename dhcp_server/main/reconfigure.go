@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/polarbroadband/rp1/proto/dhcp"
+)
+
+// RFC 8415 section 20.4 Reconfigure Key Authentication Protocol
+const (
+	AUTH_PROTOCOL_RECONFIGURE = 3
+	AUTH_ALGORITHM_HMAC_MD5   = 1
+	AUTH_RDM_MONOTONIC        = 0
+	AUTH_TYPE_KEY             = 1
+	AUTH_TYPE_HMAC_MD5        = 2
+	RECONFIGURE_KEY_LEN       = 16
+)
+
+var (
+	// RFC 8415 section 7.6 REC_TIMEOUT and REC_MAX_RC
+	REC_TIMEOUT = 2 * time.Second
+	REC_MAX_RC  = 8
+	// pause between the Reconfigure messages of a network change
+	RECONFIGURE_INTERVAL = 10 * time.Millisecond
+)
+
+// OptAuthReconfigure builds the Authentication option of the Reconfigure Key
+// protocol, carrying the key or the HMAC-MD5 digest
+func OptAuthReconfigure(infoType byte, info []byte) *dhcpv6.OptionGeneric {
+	data := []byte{AUTH_PROTOCOL_RECONFIGURE, AUTH_ALGORITHM_HMAC_MD5, AUTH_RDM_MONOTONIC}
+	// replay detection, monotonic across replicas as long as clocks are
+	data = binary.BigEndian.AppendUint64(data, uint64(time.Now().UnixNano()))
+	data = append(data, infoType)
+	data = append(data, info...)
+	return &dhcpv6.OptionGeneric{OptionCode: dhcpv6.OptionAuth, OptionData: data}
+}
+
+// acceptReconfigure hands the Reconfigure key of the client out in the Reply
+// when the client accepts Reconfigure messages, the key is kept with every
+// binding of the Reply
+func (s *DhcpServer) acceptReconfigure(t *Txn, reply *dhcpv6.Message) {
+	if t.Msg.GetOneOption(dhcpv6.OptionReconfAccept) == nil {
+		return
+	}
+	keys := []string{}
+	for _, ia := range reply.Options.IANA() {
+		if len(ia.Options.Addresses()) > 0 {
+			keys = append(keys, ClientKey(t.DUID, IaidString(ia.IaId)))
+		}
+	}
+	for _, ia := range reply.Options.IAPD() {
+		if len(ia.Options.Prefixes()) > 0 {
+			keys = append(keys, PrefixKey(t.DUID, IaidString(ia.IaId)))
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	var rk []byte
+	for _, k := range keys {
+		if b := s.Leases.Find(k); b != nil && len(b.ReconfigureKey) == RECONFIGURE_KEY_LEN {
+			rk = b.ReconfigureKey
+			break
+		}
+	}
+	if rk == nil {
+		rk = make([]byte, RECONFIGURE_KEY_LEN)
+		if _, err := rand.Read(rk); err != nil {
+			t.Log.Errorf("unable to generate reconfigure key: %v", err)
+			return
+		}
+	}
+	for _, k := range keys {
+		if err := s.Leases.SetReconfigureKey(k, rk); err != nil {
+			t.Log.Errorf("unable to save reconfigure key: %v", err)
+			return
+		}
+	}
+	reply.AddOption(&dhcpv6.OptionGeneric{OptionCode: dhcpv6.OptionReconfAccept})
+	reply.AddOption(OptAuthReconfigure(AUTH_TYPE_KEY, rk))
+}
+
+// SetReconfigureKey keeps the Reconfigure key of the client with the binding
+// of a client IA by ClientKey or PrefixKey
+func (db *LeaseDB) SetReconfigureKey(key string, rk []byte) error {
+	db.Locker.Lock()
+	defer db.Locker.Unlock()
+	b, ok := db.Bindings[key]
+	if !ok || b.State != StateBound {
+		return ErrNoBinding
+	}
+	if bytes.Equal(b.ReconfigureKey, rk) {
+		return nil
+	}
+	b.ReconfigureKey = rk
+	return db.persist(b)
+}
+
+// ChangedOptions returns the networks of the config whose options, or the
+// options of their reservations, differ from the ones being served
+func (db *LeaseDB) ChangedOptions(cfg *dhcp.Config) []string {
+	db.Locker.RLock()
+	defer db.Locker.RUnlock()
+	changed := []string{}
+	for name, n := range cfg.GetNetworks() {
+		s, ok := db.Scopes[name]
+		if !ok || s.IPv4() {
+			continue
+		}
+		same := proto.Equal(s.Network.GetOptions(), n.GetOptions()) &&
+			len(s.Network.GetReservations()) == len(n.GetReservations())
+		for i, r := range n.GetReservations() {
+			same = same && proto.Equal(s.Network.GetReservations()[i].GetOptions(), r.GetOptions())
+		}
+		if !same {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// Reconfigure asks the clients of the networks holding a Reconfigure key to
+// renew their bindings and pick up the new options, only the leader does
+func (s *DhcpServer) Reconfigure(networks []string) {
+	if s.Standby() {
+		return
+	}
+	for _, name := range networks {
+		clients := map[string]*Binding{}
+		for _, b := range s.Leases.Search(LeaseFilter{Network: name, State: StateBound.String(), IPv6: true}) {
+			// addresses only, a delegated prefix is not the client address
+			if _, ok := clients[b.DUID]; !ok && b.PrefixLen == 0 && len(b.ReconfigureKey) == RECONFIGURE_KEY_LEN {
+				clients[b.DUID] = b
+			}
+		}
+		s.Log.WithField("network", name).Infof("options changed, reconfigure %v clients", len(clients))
+		for _, b := range clients {
+			go s.reconfigure(b)
+			time.Sleep(RECONFIGURE_INTERVAL)
+		}
+	}
+}
+
+// reconfigure sends a Reconfigure to the client address of the binding until
+// the client renews, as RFC 8415 section 18.3.11 defines it
+func (s *DhcpServer) reconfigure(b *Binding) {
+	log := s.Log.WithFields(BindingFields(b)).WithField("duid", b.DUID)
+	duid, err := hex.DecodeString(b.DUID)
+	if err != nil {
+		log.Errorf("invalid client DUID: %v", err)
+		return
+	}
+	conn, err := net.DialUDP("udp6", nil, &net.UDPAddr{IP: b.Addr, Port: dhcpv6.DefaultClientPort})
+	if err != nil {
+		log.Errorf("unable to reach client: %v", err)
+		return
+	}
+	defer conn.Close()
+	key := ClientKey(b.DUID, b.IAID)
+	timeout := REC_TIMEOUT
+	for rc := 0; rc < REC_MAX_RC && !s.Standby(); rc++ {
+		msg, err := NewReconfigure(s.DUID, duid, b.ReconfigureKey)
+		if err != nil {
+			log.Errorf("unable to build reconfigure: %v", err)
+			return
+		}
+		sent := time.Now()
+		if _, err := conn.Write(msg.ToBytes()); err != nil {
+			log.Errorf("failed to send %s: %v", msg.Type(), err)
+			return
+		}
+		s.Response.With(prometheus.Labels{"family": "v6", "type": dhcp.MetricLabel(msg.Type().String())}).Inc()
+		log.Infof("%s sent", msg.Type())
+		time.Sleep(timeout)
+		// renewed by the client through any replica
+		if r := s.Leases.Find(key); r == nil || r.Expire.Add(-r.Valid).After(sent) {
+			return
+		}
+		timeout *= 2
+	}
+	log.Warn("client did not renew on reconfigure")
+}
+
+// NewReconfigure builds a Reconfigure asking the client to renew, signed with
+// its Reconfigure key
+func NewReconfigure(serverID dhcpv6.Duid, clientID, rk []byte) (*dhcpv6.Message, error) {
+	if len(rk) != RECONFIGURE_KEY_LEN {
+		return nil, fmt.Errorf("invalid reconfigure key length %v", len(rk))
+	}
+	msg := &dhcpv6.Message{MessageType: dhcpv6.MessageTypeReconfigure}
+	msg.AddOption(dhcpv6.OptServerID(serverID))
+	msg.AddOption(&dhcpv6.OptionGeneric{OptionCode: dhcpv6.OptionClientID, OptionData: clientID})
+	msg.AddOption(&dhcpv6.OptionGeneric{OptionCode: dhcpv6.OptionReconfMessage, OptionData: []byte{byte(dhcpv6.MessageTypeRenew)}})
+	// the digest is computed over the message with a zeroed digest field
+	auth := OptAuthReconfigure(AUTH_TYPE_HMAC_MD5, make([]byte, md5.Size))
+	msg.AddOption(auth)
+	mac := hmac.New(md5.New, rk)
+	mac.Write(msg.ToBytes())
+	copy(auth.OptionData[len(auth.OptionData)-md5.Size:], mac.Sum(nil))
+	return msg, nil
+}
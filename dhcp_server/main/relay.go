@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+)
+
+var (
+	// RFC 8415 section 7.6 HOP_COUNT_LIMIT
+	HOP_COUNT_LIMIT = 8
+)
+
+// RelayChain returns the Relay-forward messages wrapping a client message,
+// outermost first
+func RelayChain(r dhcpv6.DHCPv6) ([]*dhcpv6.RelayMessage, error) {
+	chain := []*dhcpv6.RelayMessage{}
+	for r.IsRelay() {
+		relay, ok := r.(*dhcpv6.RelayMessage)
+		if !ok || relay.Type() != dhcpv6.MessageTypeRelayForward {
+			return nil, fmt.Errorf("unexpected %s in relay chain", r.Type())
+		}
+		if len(chain) > HOP_COUNT_LIMIT {
+			return nil, fmt.Errorf("relay chain exceeds %v hops", HOP_COUNT_LIMIT)
+		}
+		chain = append(chain, relay)
+		inner := relay.Options.RelayMessage()
+		if inner == nil {
+			return nil, fmt.Errorf("relay %v without relay message option", relay.LinkAddr)
+		}
+		r = inner
+	}
+	return chain, nil
+}
+
+// RelayReply wraps the response into one Relay-reply for every Relay-forward
+// of the chain, innermost first, as RFC 8415 section 19.3 defines it. The hop
+// count, link and peer addresses and interface-id are copied from each hop
+func RelayReply(chain []*dhcpv6.RelayMessage, resp *dhcpv6.Message) dhcpv6.DHCPv6 {
+	var out dhcpv6.DHCPv6 = resp
+	for i := len(chain) - 1; i >= 0; i-- {
+		forw := chain[i]
+		repl := &dhcpv6.RelayMessage{
+			MessageType: dhcpv6.MessageTypeRelayReply,
+			HopCount:    forw.HopCount,
+			LinkAddr:    forw.LinkAddr,
+			PeerAddr:    forw.PeerAddr,
+		}
+		if ifid := forw.GetOneOption(dhcpv6.OptionInterfaceID); ifid != nil {
+			repl.AddOption(ifid)
+		}
+		repl.AddOption(dhcpv6.OptRelayMessage(out))
+		out = repl
+	}
+	return out
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+)
+
+// relayForward wraps the message into a Relay-forward of the link, with an
+// interface-id if set
+func relayForward(t *testing.T, inner dhcpv6.DHCPv6, link string, ifid []byte) *dhcpv6.RelayMessage {
+	t.Helper()
+	r, err := dhcpv6.EncapsulateRelay(inner, dhcpv6.MessageTypeRelayForward, net.ParseIP(link), net.ParseIP("fe80::1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ifid != nil {
+		r.AddOption(dhcpv6.OptInterfaceID(ifid))
+	}
+	return r
+}
+
+func TestNewTxnLinkAddr(t *testing.T) {
+	msg := clientMessage(dhcpv6.MessageTypeSolicit)
+	for _, tc := range []struct {
+		name string
+		// relay link-addresses and interface-ids, innermost first
+		links []string
+		ifids [][]byte
+		link  net.IP
+		ifid  []byte
+	}{
+		{"direct", nil, nil, nil, nil},
+		{"one relay", []string{"2001:db8:1::1"}, [][]byte{[]byte("eth0")}, net.ParseIP("2001:db8:1::1"), []byte("eth0")},
+		{"two relays", []string{"2001:db8:1::1", "2001:db8:2::1"}, [][]byte{[]byte("eth0"), []byte("eth1")}, net.ParseIP("2001:db8:1::1"), []byte("eth0")},
+		{"lightweight relay", []string{"::", "2001:db8:2::1"}, [][]byte{[]byte("port1"), []byte("eth1")}, net.ParseIP("2001:db8:2::1"), []byte("port1")},
+		{"lightweight relay only", []string{"::"}, [][]byte{[]byte("port1")}, nil, []byte("port1")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var r dhcpv6.DHCPv6 = msg
+			for i, link := range tc.links {
+				r = relayForward(t, r, link, tc.ifids[i])
+			}
+			txn, err := NewTxn(r, testLog())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(txn.Relays) != len(tc.links) {
+				t.Errorf("%v relays, want %v", len(txn.Relays), len(tc.links))
+			}
+			if !txn.LinkAddr.Equal(tc.link) {
+				t.Errorf("link-address %v, want %v", txn.LinkAddr, tc.link)
+			}
+			if !bytes.Equal(txn.InterfaceID, tc.ifid) {
+				t.Errorf("interface-id %q, want %q", txn.InterfaceID, tc.ifid)
+			}
+		})
+	}
+}
+
+func TestRelayChain(t *testing.T) {
+	msg := &dhcpv6.Message{MessageType: dhcpv6.MessageTypeSolicit, TransactionID: dhcpv6.TransactionID{1, 2, 3}}
+	deep := dhcpv6.DHCPv6(msg)
+	for i := 0; i <= HOP_COUNT_LIMIT+1; i++ {
+		deep = relayForward(t, deep, "2001:db8:1::1", nil)
+	}
+	repl, err := dhcpv6.EncapsulateRelay(msg, dhcpv6.MessageTypeRelayReply, net.ParseIP("2001:db8:1::1"), net.ParseIP("fe80::1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		msg  dhcpv6.DHCPv6
+		// link-addresses of the chain, outermost first
+		links []string
+		ok    bool
+	}{
+		{"direct", msg, []string{}, true},
+		{"one relay", relayForward(t, msg, "2001:db8:1::1", nil), []string{"2001:db8:1::1"}, true},
+		{"two relays", relayForward(t, relayForward(t, msg, "2001:db8:1::1", nil), "2001:db8:2::1", nil), []string{"2001:db8:2::1", "2001:db8:1::1"}, true},
+		{"relay-reply in chain", relayForward(t, repl, "2001:db8:2::1", nil), nil, false},
+		{"without relay message", &dhcpv6.RelayMessage{MessageType: dhcpv6.MessageTypeRelayForward}, nil, false},
+		{"too many hops", deep, nil, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			chain, err := RelayChain(tc.msg)
+			if (err == nil) != tc.ok {
+				t.Fatalf("error %v, want ok %v", err, tc.ok)
+			}
+			if len(chain) != len(tc.links) {
+				t.Fatalf("%v relays, want %v", len(chain), len(tc.links))
+			}
+			for i, link := range tc.links {
+				if !chain[i].LinkAddr.Equal(net.ParseIP(link)) {
+					t.Errorf("relay %v link-address %v, want %s", i, chain[i].LinkAddr, link)
+				}
+			}
+		})
+	}
+}
+
+func TestRelayReply(t *testing.T) {
+	msg := &dhcpv6.Message{MessageType: dhcpv6.MessageTypeSolicit, TransactionID: dhcpv6.TransactionID{1, 2, 3}}
+	resp := &dhcpv6.Message{MessageType: dhcpv6.MessageTypeAdvertise, TransactionID: msg.TransactionID}
+	for _, tc := range []struct {
+		name  string
+		links []string // innermost first
+		ifids [][]byte
+	}{
+		{"direct", nil, nil},
+		{"one relay", []string{"2001:db8:1::1"}, [][]byte{[]byte("eth0")}},
+		{"relay without interface-id", []string{"2001:db8:1::1"}, [][]byte{nil}},
+		{"two relays", []string{"::", "2001:db8:2::1"}, [][]byte{[]byte("port1"), []byte("eth1")}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var r dhcpv6.DHCPv6 = msg
+			for i, link := range tc.links {
+				r = relayForward(t, r, link, tc.ifids[i])
+			}
+			chain, err := RelayChain(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the reply goes through the wire and back, as the relay gets it
+			out, err := dhcpv6.FromBytes(RelayReply(chain, resp).ToBytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, forw := range chain {
+				repl, ok := out.(*dhcpv6.RelayMessage)
+				if !ok || repl.Type() != dhcpv6.MessageTypeRelayReply {
+					t.Fatalf("hop %v is %s, want RELAY-REPL", i, out.Type())
+				}
+				if repl.HopCount != forw.HopCount || !repl.LinkAddr.Equal(forw.LinkAddr) || !repl.PeerAddr.Equal(forw.PeerAddr) {
+					t.Errorf("hop %v %v %v %v, want %v %v %v", i, repl.HopCount, repl.LinkAddr, repl.PeerAddr, forw.HopCount, forw.LinkAddr, forw.PeerAddr)
+				}
+				if !bytes.Equal(repl.Options.InterfaceID(), forw.Options.InterfaceID()) {
+					t.Errorf("hop %v interface-id %q, want %q", i, repl.Options.InterfaceID(), forw.Options.InterfaceID())
+				}
+				out = repl.Options.RelayMessage()
+			}
+			inner, ok := out.(*dhcpv6.Message)
+			if !ok || inner.Type() != resp.Type() || inner.TransactionID != resp.TransactionID {
+				t.Errorf("relayed %v, want %v", out, resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/polarbroadband/rp1/etcdlib"
+	"github.com/polarbroadband/rp1/proto/dhcp"
+	etcd "go.etcd.io/etcd/client/v3"
+)
+
+var (
+	LEASE_DIR = "lease"
+	// etcd refuses lease TTL below 5 seconds, see etcdlib.KvDepot.Put
+	MIN_LEASE_TTL int64 = 5
+)
+
+func leaseKey(addrKey string) string {
+	return LEASE_DIR + "/" + addrKey
+}
+
+func (b *Binding) ToLease() *dhcp.Lease {
+	return &dhcp.Lease{
+		Network:           b.Network,
+		DUID:              b.DUID,
+		IAID:              b.IAID,
+		Addr:              b.Addr.String(),
+		PrefixLen:         int32(b.PrefixLen),
+		ValidLifetime:     int64(b.Valid / time.Second),
+		PreferredLifetime: int64(b.Preferred / time.Second),
+		Expire:            b.Expire.Unix(),
+		State:             b.State.String(),
+		ReconfigureKey:    hex.EncodeToString(b.ReconfigureKey),
+	}
+}
+
+func NewBindingFromLease(l *dhcp.Lease) (*Binding, error) {
+	addr := net.ParseIP(l.GetAddr())
+	if addr == nil {
+		return nil, fmt.Errorf("invalid lease address %s", l.GetAddr())
+	}
+	rk, err := hex.DecodeString(l.GetReconfigureKey())
+	if err != nil {
+		return nil, fmt.Errorf("invalid lease reconfigure key %s", l.GetReconfigureKey())
+	}
+	state := StateBound
+	if l.GetState() == StateDeclined.String() {
+		state = StateDeclined
+	}
+	return &Binding{
+		Network:   l.GetNetwork(),
+		DUID:      l.GetDUID(),
+		IAID:      l.GetIAID(),
+		Addr:      addr.To16(),
+		PrefixLen: int(l.GetPrefixLen()),
+		Valid:     time.Duration(l.GetValidLifetime()) * time.Second,
+		Preferred: time.Duration(l.GetPreferredLifetime()) * time.Second,
+		Expire:    time.Unix(l.GetExpire(), 0),
+		State:     state,
+
+		ReconfigureKey: rk,
+	}, nil
+}
+
+// persist saves a bound binding in etcd, the etcd lease expires the key
+// together with the binding. Each save grants a new lease, the previous one
+// of the binding is revoked once the key moved off it
+func (db *LeaseDB) persist(b *Binding) error {
+	if db.Depot == nil {
+		return nil
+	}
+	out, err := proto.Marshal(b.ToLease())
+	if err != nil {
+		return fmt.Errorf("unable to serialize lease %v", err)
+	}
+	ttl := int64(time.Until(b.Expire) / time.Second)
+	if ttl < MIN_LEASE_TTL {
+		ttl = MIN_LEASE_TTL
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), db.Depot.OprTimeout)
+	defer cancel()
+	lease, err := db.Depot.Conn.Grant(ctx, ttl)
+	if err != nil {
+		return fmt.Errorf("unable to grant lease %v", err)
+	}
+	key := leaseKey(b.AddrKey())
+	if db.Election != nil {
+		err = db.Election.Put(key, string(out), int64(lease.ID))
+	} else if _, err = db.Depot.Conn.Put(ctx, db.Depot.Depot+"/"+key, string(out), etcd.WithLease(lease.ID)); err != nil {
+		err = fmt.Errorf("unable to save key %s %v", key, err)
+	}
+	if err == ErrNotLeader {
+		// nothing was put, the lease would be left over until its TTL
+		db.revoke(int64(lease.ID))
+	}
+	if err != nil {
+		return err
+	}
+	if b.Lease != 0 && b.Lease != int64(lease.ID) {
+		db.revoke(b.Lease)
+	}
+	b.Lease = int64(lease.ID)
+	return nil
+}
+
+func (db *LeaseDB) unpersist(b *Binding) {
+	if db.Depot == nil || b.State == StateOffered {
+		return
+	}
+	del := db.Depot.Delete
+	if db.Election != nil {
+		del = db.Election.Delete
+	}
+	if err := del(leaseKey(b.AddrKey())); err != nil {
+		db.Log.WithFields(BindingFields(b)).Errorf("unable to remove lease: %v", err)
+		return
+	}
+	if b.Lease != 0 {
+		db.revoke(b.Lease)
+		b.Lease = 0
+	}
+}
+
+// revoke drops an etcd lease no key of the binding is attached to anymore
+func (db *LeaseDB) revoke(lease int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), db.Depot.OprTimeout)
+	defer cancel()
+	if _, err := db.Depot.Conn.Revoke(ctx, etcd.LeaseID(lease)); err != nil {
+		db.Log.Warnf("unable to revoke lease %x: %v", lease, err)
+	}
+}
+
+// Restore loads the bindings saved in etcd
+func (db *LeaseDB) Restore(kvs []*etcdlib.KV) {
+	db.Locker.Lock()
+	defer db.Locker.Unlock()
+	for _, kv := range kvs {
+		if err := db.apply(kv.Value, kv.Lease); err != nil {
+			db.Log.Warnf("skip lease %s: %v", kv.Key, err)
+		}
+	}
+	db.Log.Infof("%v leases restored", len(db.Bindings))
+}
+
+// Mirror keeps the bindings in sync with the changes made to the lease
+// directory, by this or any other server replica
+func (db *LeaseDB) Mirror(ch etcd.WatchChan) {
+	for wresp := range ch {
+		for _, ev := range wresp.Events {
+			db.Locker.Lock()
+			switch ev.Type {
+			case etcd.EventTypePut:
+				if err := db.apply(ev.Kv.Value, ev.Kv.Lease); err != nil {
+					db.Log.Errorf("received invalid lease %s: %v", ev.Kv.Key, err)
+				}
+			case etcd.EventTypeDelete:
+				addr := strings.TrimPrefix(string(ev.Kv.Key), db.Depot.Depot+"/"+LEASE_DIR+"/")
+				if b, ok := db.Addrs[addr]; ok && b.State != StateOffered {
+					db.release(b)
+				}
+			}
+			db.Locker.Unlock()
+		}
+	}
+}
+
+// apply installs a serialized lease saved with the etcd lease, the caller must
+// hold the lock
+func (db *LeaseDB) apply(data []byte, lease int64) error {
+	l := &dhcp.Lease{}
+	if err := proto.Unmarshal(data, l); err != nil {
+		return err
+	}
+	b, err := NewBindingFromLease(l)
+	if err != nil {
+		return err
+	}
+	b.Lease = lease
+	if old, ok := db.Addrs[b.AddrKey()]; ok {
+		db.release(old)
+	}
+	if b.State == StateDeclined {
+		db.Addrs[b.AddrKey()] = b
+		return nil
+	}
+	if old, ok := db.Bindings[b.Key()]; ok {
+		db.release(old)
+	}
+	db.Bindings[b.Key()] = b
+	db.Addrs[b.AddrKey()] = b
+	return nil
+}
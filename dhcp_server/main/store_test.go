@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/polarbroadband/rp1/etcdlib"
+	"github.com/polarbroadband/rp1/proto/dhcp"
+)
+
+func TestRestoreThenUpdate(t *testing.T) {
+	lan := &dhcp.Network{Prefix: "2001:db8::/64", Pools: []*dhcp.Pool{{Begin: "2001:db8::10", End: "2001:db8::20"}}}
+	moved := &dhcp.Network{Prefix: "2001:db8::/64", Pools: []*dhcp.Pool{{Begin: "2001:db8::100", End: "2001:db8::200"}}}
+	for _, tc := range []struct {
+		name  string
+		lease *dhcp.Lease
+		cfg   *dhcp.Config
+		ok    bool
+	}{
+		{"same networks", &dhcp.Lease{Network: "lan", Addr: "2001:db8::10", State: "bound"}, &dhcp.Config{Networks: map[string]*dhcp.Network{"lan": lan}}, true},
+		{"network removed", &dhcp.Lease{Network: "lan", Addr: "2001:db8::10", State: "bound"}, &dhcp.Config{}, false},
+		{"pool moved", &dhcp.Lease{Network: "lan", Addr: "2001:db8::10", State: "bound"}, &dhcp.Config{Networks: map[string]*dhcp.Network{"lan": moved}}, false},
+		{"declined address", &dhcp.Lease{Network: "lan", Addr: "2001:db8::10", State: "declined"}, &dhcp.Config{}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := NewLeaseDB(&dhcp.Config{}, testLog())
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.lease.DUID, tc.lease.IAID = "0003000102000000000002", "00000001"
+			tc.lease.Expire = time.Now().Add(time.Hour).Unix()
+			data, err := proto.Marshal(tc.lease)
+			if err != nil {
+				t.Fatal(err)
+			}
+			db.Restore([]*etcdlib.KV{{Key: "lease/" + tc.lease.Addr, Value: data, Lease: 7}})
+			// the etcd lease is revoked once the binding is saved again
+			if b := db.Addrs[AddrKey(net.ParseIP(tc.lease.Addr), 0)]; b == nil || b.Lease != 7 {
+				t.Fatalf("restored %v, want etcd lease 7", b)
+			}
+			if err := db.Update(tc.cfg); (err == nil) != tc.ok {
+				t.Errorf("Update error %v, want ok %v", err, tc.ok)
+			}
+			// a refused config is not loaded, the server keeps running without it
+			if want := len(tc.cfg.GetNetworks()); !tc.ok {
+				if len(db.Scopes) != 0 {
+					t.Errorf("networks %v loaded", db.Scopes)
+				}
+			} else if len(db.Scopes) != want {
+				t.Errorf("%v networks loaded, want %v", len(db.Scopes), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/polarbroadband/rp1/proto/dhcp"
+	"github.com/sirupsen/logrus"
+)
+
+// Client is what identifies a client to its reservation: its DUID, its
+// link-layer address and the identifiers added by the relay agent nearest to
+// it, within the network it was placed in
+type Client struct {
+	Scope       *Scope
+	DUID        string
+	MAC         net.HardwareAddr
+	InterfaceID []byte
+	RemoteID    []byte
+	Log         *logrus.Entry
+}
+
+// Txn is a DHCPv6 client message being served, with the relay chain it came
+// through and the link-address of the relay nearest to the client that sets one
+type Txn struct {
+	Client
+	Msg      *dhcpv6.Message
+	Relays   []*dhcpv6.RelayMessage // outermost first
+	LinkAddr net.IP
+	Peer     net.IP // sender of the message, the outermost relay if relayed
+}
+
+// NewTxn decapsulates a client message, the log entry of the transaction
+// carries its ID, the client DUID and the relay link-address
+func NewTxn(r dhcpv6.DHCPv6, log *logrus.Entry) (*Txn, error) {
+	relays, err := RelayChain(r)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := r.GetInnerMessage()
+	if err != nil {
+		return nil, err
+	}
+	t := Txn{
+		Client: Client{DUID: DuidString(msg.Options.ClientID())},
+		Msg:    msg,
+		Relays: relays,
+	}
+	if duid := msg.Options.ClientID(); duid != nil && (duid.Type == dhcpv6.DUID_LL || duid.Type == dhcpv6.DUID_LLT) {
+		t.MAC = duid.LinkLayerAddr
+	}
+	for _, relay := range relays {
+		// the last relay seen is the one nearest to the client, except for the
+		// link-address a lightweight relay agent leaves unspecified (RFC 6221)
+		if relay.LinkAddr != nil && !relay.LinkAddr.IsUnspecified() {
+			t.LinkAddr = relay.LinkAddr
+		}
+		t.InterfaceID = relay.Options.InterfaceID()
+		t.RemoteID = nil
+		if rid := relay.Options.RemoteID(); rid != nil {
+			t.RemoteID = rid.RemoteID
+		}
+		if _, mac := relay.Options.ClientLinkLayerAddress(); mac != nil && t.MAC == nil {
+			t.MAC = mac
+		}
+	}
+	t.Log = log.WithFields(logrus.Fields{
+		"xid":  msg.TransactionID.String(),
+		"type": msg.Type().String(),
+		"duid": t.DUID,
+	})
+	if t.Relayed() {
+		t.Log = t.Log.WithField("link", t.LinkAddr.String())
+	}
+	return &t, nil
+}
+
+// Reservation returns the reservation of the scope matching the client, nil
+// if none
+func (c *Client) Reservation() *dhcp.Reservation {
+	if c.Scope == nil {
+		return nil
+	}
+	for _, r := range c.Scope.Network.GetReservations() {
+		if r.GetDUID() != "" && r.GetDUID() == c.DUID {
+			return r
+		}
+		if mac, err := net.ParseMAC(r.GetMAC()); err == nil && c.MAC != nil && bytes.Equal(mac, c.MAC) {
+			return r
+		}
+		if id, err := hex.DecodeString(r.GetInterfaceID()); err == nil && len(id) > 0 && bytes.Equal(id, c.InterfaceID) {
+			return r
+		}
+		if id, err := hex.DecodeString(r.GetRemoteID()); err == nil && len(id) > 0 && bytes.Equal(id, c.RemoteID) {
+			return r
+		}
+	}
+	return nil
+}
+
+// ReservedAddr returns the address reserved to the client, nil if none
+func (c *Client) ReservedAddr() net.IP {
+	return net.ParseIP(c.Reservation().GetAddr())
+}
+
+// ReservedPrefix returns the prefix reserved to the client, nil if none
+func (c *Client) ReservedPrefix() *net.IPNet {
+	_, p, err := net.ParseCIDR(c.Reservation().GetPrefix())
+	if err != nil {
+		return nil
+	}
+	return p
+}
+
+// Options returns the option set of the client network, overlaid with the
+// one of its reservation
+func (c *Client) Options() *dhcp.Options {
+	if c.Scope == nil {
+		return nil
+	}
+	return MergeOptions(c.Scope.Network.GetOptions(), c.Reservation().GetOptions())
+}
+
+// Relayed reports whether the client message came through relay agents
+func (t *Txn) Relayed() bool {
+	return len(t.Relays) > 0
+}
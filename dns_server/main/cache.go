@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	pkgdns "github.com/miekg/dns"
+)
+
+var (
+	DEFAULT_CACHE_SIZE = 10000
+	// longest time a forwarded response is cached, whatever its TTLs
+	MAX_CACHE_TTL = 24 * time.Hour
+)
+
+type cacheEntry struct {
+	msg    *pkgdns.Msg
+	stored time.Time
+	expire time.Time
+}
+
+// Cache keeps the forwarded responses for the TTL of their records, and
+// negative responses for the SOA minimum as RFC 2308 defines it
+type Cache struct {
+	Size int
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+func NewCache(size int) *Cache {
+	return &Cache{Size: size, entries: make(map[string]*cacheEntry)}
+}
+
+// CacheKey identifies the responses to the question of the query, DNSSEC
+// records only go to clients asking for them
+func CacheKey(r *pkgdns.Msg) string {
+	q := r.Question[0]
+	do := false
+	if opt := r.IsEdns0(); opt != nil {
+		do = opt.Do()
+	}
+	return fmt.Sprintf("%s/%d/%d/%v/%v", strings.ToLower(q.Name), q.Qtype, q.Qclass, do, r.CheckingDisabled)
+}
+
+// Get returns a copy of the cached response with its TTLs lowered by the
+// time spent in the cache, nil when missing or expired
+func (c *Cache) Get(key string) *pkgdns.Msg {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	if !now.Before(e.expire) {
+		delete(c.entries, key)
+		return nil
+	}
+	age := uint32(now.Sub(e.stored).Seconds())
+	msg := e.msg.Copy()
+	for _, section := range [][]pkgdns.RR{msg.Answer, msg.Ns, msg.Extra} {
+		for _, rr := range section {
+			if rr.Header().Ttl > age {
+				rr.Header().Ttl -= age
+			} else {
+				rr.Header().Ttl = 0
+			}
+		}
+	}
+	return msg
+}
+
+// Put caches the response for its TTL, nothing is cached for responses
+// without one
+func (c *Cache) Put(key string, msg *pkgdns.Msg) {
+	ttl, ok := ResponseTTL(msg)
+	if !ok || ttl == 0 || c.Size <= 0 {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.Size {
+		for k, e := range c.entries {
+			if !now.Before(e.expire) {
+				delete(c.entries, k)
+			}
+		}
+		// random eviction out of the map order
+		for k := range c.entries {
+			if len(c.entries) < c.Size {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = &cacheEntry{msg: msg.Copy(), stored: now, expire: now.Add(ttl)}
+}
+
+// Len returns the number of cached responses
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// ResponseTTL returns how long the response may be cached: the lowest TTL of
+// the answer, or the lesser of the SOA TTL and minimum for NXDOMAIN and
+// NODATA. Failures are not cached
+func ResponseTTL(msg *pkgdns.Msg) (time.Duration, bool) {
+	if msg.Truncated || (msg.Rcode != pkgdns.RcodeSuccess && msg.Rcode != pkgdns.RcodeNameError) {
+		return 0, false
+	}
+	ttl, found := uint32(0), false
+	lower := func(t uint32) {
+		if !found || t < ttl {
+			ttl, found = t, true
+		}
+	}
+	if msg.Rcode == pkgdns.RcodeSuccess && len(msg.Answer) > 0 {
+		for _, rr := range msg.Answer {
+			lower(rr.Header().Ttl)
+		}
+	} else {
+		for _, rr := range msg.Ns {
+			if soa, ok := rr.(*pkgdns.SOA); ok {
+				lower(soa.Hdr.Ttl)
+				lower(soa.Minttl)
+			}
+		}
+	}
+	if !found {
+		return 0, false
+	}
+	d := time.Duration(ttl) * time.Second
+	if d > MAX_CACHE_TTL {
+		d = MAX_CACHE_TTL
+	}
+	return d, true
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pkgdns "github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"github.com/polarbroadband/rp1/proto/dns"
+)
+
+var (
+	UPSTREAM_TIMEOUT = 2 * time.Second
+	// consecutive failures an upstream is taken out of rotation after
+	UPSTREAM_MAX_FAILURES = 3
+	// period of the health check of every upstream
+	UPSTREAM_CHECK_INTERVAL = 10 * time.Second
+
+	ErrNoUpstream = errors.New("no upstream")
+)
+
+// Upstream is a recursive server queries are forwarded to
+type Upstream struct {
+	Addr string
+
+	mu       sync.Mutex
+	up       bool
+	failures int
+}
+
+// Up tells whether the upstream is in rotation
+func (u *Upstream) Up() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.up
+}
+
+// Report counts the outcome of a query, the upstream is out of rotation
+// after UPSTREAM_MAX_FAILURES consecutive failures and back with a success
+func (u *Upstream) Report(err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err == nil {
+		u.up, u.failures = true, 0
+		return
+	}
+	if u.failures++; u.failures >= UPSTREAM_MAX_FAILURES {
+		u.up = false
+	}
+}
+
+// Forwarder holds the upstreams of a zone
+type Forwarder struct {
+	Zone      string
+	Upstreams []*Upstream
+
+	next uint32
+}
+
+// Candidates returns the upstreams to try in order, the ones in rotation
+// round robin and then the others as a last resort
+func (f *Forwarder) Candidates() []*Upstream {
+	n := len(f.Upstreams)
+	start := int(atomic.AddUint32(&f.next, 1)) % n
+	up, down := []*Upstream{}, []*Upstream{}
+	for i := 0; i < n; i++ {
+		u := f.Upstreams[(start+i)%n]
+		if u.Up() {
+			up = append(up, u)
+		} else {
+			down = append(down, u)
+		}
+	}
+	return append(up, down...)
+}
+
+// Resolver forwards recursive queries for names out of the zones served
+// authoritatively to the upstreams of the closest forwarder zone, through
+// the cache
+type Resolver struct {
+	Cache *Cache
+	Log   *logrus.Entry
+
+	Forwarded *prometheus.CounterVec
+	Cached    *prometheus.CounterVec
+	UpGauge   *prometheus.GaugeVec
+
+	mu         sync.RWMutex
+	forwarders map[string]*Forwarder
+	// upstreams by address, their health outlives config updates
+	upstreams map[string]*Upstream
+}
+
+func NewResolver(reg prometheus.Registerer, cacheSize int, log *logrus.Entry) *Resolver {
+	r := &Resolver{
+		Cache: NewCache(cacheSize),
+		Log:   log,
+		Forwarded: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "dns_forwarded_requests_total",
+				Help: "Number of queries forwarded to upstreams, by result",
+			},
+			[]string{"upstream", "result"},
+		),
+		Cached: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "dns_cache_requests_total",
+				Help: "Number of cache lookups of recursive queries, by result",
+			},
+			[]string{"result"},
+		),
+		UpGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "dns_upstream_up",
+				Help: "Whether the upstream is in rotation",
+			},
+			[]string{"upstream"},
+		),
+		forwarders: make(map[string]*Forwarder),
+		upstreams:  make(map[string]*Upstream),
+	}
+	reg.MustRegister(r.Forwarded)
+	reg.MustRegister(r.Cached)
+	reg.MustRegister(r.UpGauge)
+	reg.MustRegister(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "dns_cache_entries",
+			Help: "Number of cached responses",
+		},
+		func() float64 { return float64(r.Cache.Len()) },
+	))
+	return r
+}
+
+// Update replaces the forwarders with the ones of the zone config
+func (r *Resolver) Update(cfg []*dns.Forwarder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	forwarders := make(map[string]*Forwarder)
+	upstreams := make(map[string]*Upstream)
+	for _, fc := range cfg {
+		f := &Forwarder{Zone: dns.Canonical(fc.GetZone())}
+		for _, a := range fc.GetUpstreams() {
+			addr, err := dns.UpstreamAddr(a)
+			if err != nil {
+				r.Log.Errorf("forwarder zone %s: %v", f.Zone, err)
+				continue
+			}
+			u, ok := r.upstreams[addr]
+			if !ok {
+				u = &Upstream{Addr: addr, up: true}
+			}
+			upstreams[addr] = u
+			f.Upstreams = append(f.Upstreams, u)
+		}
+		if len(f.Upstreams) > 0 {
+			forwarders[f.Zone] = f
+		}
+	}
+	for addr := range r.upstreams {
+		if _, ok := upstreams[addr]; !ok {
+			r.UpGauge.DeleteLabelValues(addr)
+		}
+	}
+	r.forwarders, r.upstreams = forwarders, upstreams
+}
+
+// Enabled tells whether any forwarder is configured
+func (r *Resolver) Enabled() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.forwarders) > 0
+}
+
+// Match returns the forwarder of the closest zone of the name, nil if none
+func (r *Resolver) Match(name string) *Forwarder {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name = dns.Canonical(name)
+	for off, end := 0, false; !end; off, end = pkgdns.NextLabel(name, off) {
+		if f, ok := r.forwarders[name[off:]]; ok {
+			return f
+		}
+	}
+	return r.forwarders["."]
+}
+
+// Resolve answers the query out of the cache or else through the upstreams
+// of the forwarder. The response has no OPT record, the one of the query is
+// sent upstream with the server payload size
+func (r *Resolver) Resolve(req *pkgdns.Msg, f *Forwarder) (*pkgdns.Msg, error) {
+	key := CacheKey(req)
+	if resp := r.Cache.Get(key); resp != nil {
+		r.Cached.With(prometheus.Labels{"result": "hit"}).Inc()
+		return resp, nil
+	}
+	r.Cached.With(prometheus.Labels{"result": "miss"}).Inc()
+
+	query := req.Copy()
+	query.Id = pkgdns.Id()
+	do := false
+	if opt := query.IsEdns0(); opt != nil {
+		do = opt.Do()
+		query.Extra = withoutOPT(query.Extra)
+	}
+	query.SetEdns0(EDNS_UDP_SIZE, do)
+
+	err := ErrNoUpstream
+	for _, u := range f.Candidates() {
+		var resp *pkgdns.Msg
+		if resp, err = Exchange(query, u.Addr); err == nil && resp.Rcode == pkgdns.RcodeServerFailure {
+			err = fmt.Errorf("%s answered %s", u.Addr, pkgdns.RcodeToString[resp.Rcode])
+		}
+		u.Report(err)
+		r.UpGauge.With(prometheus.Labels{"upstream": u.Addr}).Set(boolGauge(u.Up()))
+		if err != nil {
+			r.Forwarded.With(prometheus.Labels{"upstream": u.Addr, "result": "fail"}).Inc()
+			r.Log.WithField("upstream", u.Addr).Warnf("forwarding failed: %v", err)
+			continue
+		}
+		r.Forwarded.With(prometheus.Labels{"upstream": u.Addr, "result": "success"}).Inc()
+		resp.Extra = withoutOPT(resp.Extra)
+		r.Cache.Put(key, resp)
+		return resp, nil
+	}
+	return nil, err
+}
+
+// Exchange sends the query to the upstream over UDP, and again over TCP when
+// the response is truncated
+func Exchange(query *pkgdns.Msg, addr string) (*pkgdns.Msg, error) {
+	c := &pkgdns.Client{Net: "udp", Timeout: UPSTREAM_TIMEOUT, UDPSize: EDNS_UDP_SIZE}
+	resp, _, err := c.Exchange(query, addr)
+	if err == nil && resp.Truncated {
+		c.Net = "tcp"
+		resp, _, err = c.Exchange(query, addr)
+	}
+	return resp, err
+}
+
+// Check queries every upstream for the root NS records each interval, to
+// bring the ones out of rotation back or take failing ones out, never
+// returns
+func (r *Resolver) Check(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		r.mu.RLock()
+		upstreams := make([]*Upstream, 0, len(r.upstreams))
+		for _, u := range r.upstreams {
+			upstreams = append(upstreams, u)
+		}
+		r.mu.RUnlock()
+		for _, u := range upstreams {
+			query := &pkgdns.Msg{}
+			query.SetQuestion(".", pkgdns.TypeNS)
+			resp, err := Exchange(query, u.Addr)
+			if err == nil && resp.Rcode == pkgdns.RcodeServerFailure {
+				err = fmt.Errorf("answered %s", pkgdns.RcodeToString[resp.Rcode])
+			}
+			was := u.Up()
+			u.Report(err)
+			if u.Up() != was {
+				r.Log.WithField("upstream", u.Addr).Warnf("upstream up %v, health check %v", u.Up(), err)
+			}
+			r.UpGauge.With(prometheus.Labels{"upstream": u.Addr}).Set(boolGauge(u.Up()))
+		}
+	}
+}
+
+func withoutOPT(extra []pkgdns.RR) []pkgdns.RR {
+	out := []pkgdns.RR{}
+	for _, rr := range extra {
+		if rr.Header().Rrtype != pkgdns.TypeOPT {
+			out = append(out, rr)
+		}
+	}
+	return out
+}
+
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
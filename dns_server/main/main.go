@@ -5,6 +5,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -36,11 +37,18 @@ var (
 	ETCD_USERNAME  = os.Getenv("ETCD_USERNAME")
 	ETCD_PASSWORD  = os.Getenv("ETCD_PASSWORD")
 	READY          = false
+
+	// listen address, all addresses if empty
+	DNS_ADDR = os.Getenv("DNS_ADDR")
+	// UDP payload size advertised in the EDNS0 OPT record and the largest
+	// UDP response, the DNS flag day 2020 recommendation
+	EDNS_UDP_SIZE = uint16(1232)
 )
 
 type Metrics struct {
-	AuthZone prometheus.Gauge
-	Request  *prometheus.CounterVec
+	AuthZone  prometheus.Gauge
+	Request   *prometheus.CounterVec
+	Truncated *prometheus.CounterVec
 }
 
 func NewMetrics(reg prometheus.Registerer) *Metrics {
@@ -56,9 +64,17 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 			},
 			[]string{"resolve"},
 		),
+		Truncated: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "dns_truncated_responses_total",
+				Help: "Number of responses truncated to the client buffer size",
+			},
+			[]string{"net"},
+		),
 	}
 	reg.MustRegister(m.AuthZone)
 	reg.MustRegister(m.Request)
+	reg.MustRegister(m.Truncated)
 	return m
 }
 
@@ -67,6 +83,13 @@ type BaseDNS struct {
 	*dns.Zone
 	*Metrics
 	Log *logrus.Entry
+	// records of the zone by fully qualified name
+	names map[string]*dns.Category
+	// names holding records and their ancestors up to their origin, the empty
+	// non-terminals exist too
+	nodes map[string]bool
+	// forwarding of the recursive queries out of the zones, nil if disabled
+	Resolver *Resolver
 }
 
 func (b *BaseDNS) RecordCount() (c float64) {
@@ -82,12 +105,41 @@ func (b *BaseDNS) Update(data *dns.Zone) {
 	b.Locker.Lock()
 	defer b.Locker.Unlock()
 	b.Zone = data
+	if b.Resolver != nil {
+		b.Resolver.Update(data.GetForwarders())
+	}
+	b.names = make(map[string]*dns.Category)
+	b.nodes = make(map[string]bool)
+	for name, c := range data.GetRecords() {
+		name = dns.Canonical(name)
+		b.names[name] = c
+		origin := dns.Canonical(dns.Origin(data.GetOrigins(), name))
+		labels := pkgdns.SplitDomainName(name)
+		for i := range labels {
+			n := pkgdns.Fqdn(strings.Join(labels[i:], "."))
+			b.nodes[n] = true
+			if pkgdns.CountLabel(n) <= pkgdns.CountLabel(origin) {
+				break
+			}
+		}
+	}
+}
+
+// Authoritative tells whether the name falls under an origin of the zone
+func (b *BaseDNS) Authoritative(name string) bool {
+	b.Locker.RLock()
+	defer b.Locker.RUnlock()
+	return dns.Origin(b.GetOrigins(), name) != ""
 }
 
 func (b *BaseDNS) Search(fqdn, cat string) *dns.Record {
 	b.Locker.RLock()
 	defer b.Locker.RUnlock()
-	if c, ok := b.GetRecords()[fqdn]; ok {
+	return b.search(fqdn, cat)
+}
+
+func (b *BaseDNS) search(fqdn, cat string) *dns.Record {
+	if c := b.lookup(fqdn); c != nil {
 		if v, exist := c.Type[cat]; exist {
 			return v
 		}
@@ -95,13 +147,42 @@ func (b *BaseDNS) Search(fqdn, cat string) *dns.Record {
 	return nil
 }
 
+// lookup returns the records of the name, or the ones of the wildcard of its
+// closest encloser when the name does not exist, as RFC 4592 section 3.3.1
+// describes it
+func (b *BaseDNS) lookup(fqdn string) *dns.Category {
+	name := dns.Canonical(fqdn)
+	if c, ok := b.names[name]; ok {
+		return c
+	}
+	if b.nodes[name] {
+		return nil
+	}
+	origin := dns.Canonical(dns.Origin(b.GetOrigins(), name))
+	for off, end := pkgdns.NextLabel(name, 0); !end; off, end = pkgdns.NextLabel(name, off) {
+		encloser := name[off:]
+		if b.nodes[encloser] || encloser == origin {
+			return b.names["*."+encloser]
+		}
+	}
+	return nil
+}
+
+// exists tells whether the name is in the zones, by itself or through a
+// wildcard
+func (b *BaseDNS) exists(fqdn string) bool {
+	return b.nodes[dns.Canonical(fqdn)] || b.lookup(fqdn) != nil
+}
+
 func (b *BaseDNS) String() (str string) {
 	b.Locker.RLock()
 	defer b.Locker.RUnlock()
-	str = fmt.Sprintf("\nCommit: %s", b.Commit)
+	str = fmt.Sprintf("\nCommit: %s\nOrigins: %s", b.Commit, strings.Join(b.GetOrigins(), " "))
 	for fqdn, c := range b.GetRecords() {
 		for t, v := range c.Type {
-			str = str + fmt.Sprintf("\nFQDN: %v --- Type: %v --- Addr: %+v --- TTL: %v", fqdn, t, v.GetAddr(), v.GetTTL())
+			for _, rr := range RRs(pkgdns.Fqdn(fqdn), pkgdns.StringToType[t], v) {
+				str = str + "\n" + rr.String()
+			}
 		}
 	}
 	return
@@ -110,59 +191,70 @@ func (b *BaseDNS) String() (str string) {
 func (b *BaseDNS) ServeDNS(w pkgdns.ResponseWriter, r *pkgdns.Msg) {
 	msg := pkgdns.Msg{}
 	msg.SetReply(r)
+	network := w.LocalAddr().Network()
+	// UDP responses fit in 512 bytes, or the buffer the client advertised
+	size := pkgdns.MinMsgSize
+	if network == "tcp" {
+		size = pkgdns.MaxMsgSize
+	}
+	if opt := r.IsEdns0(); opt != nil {
+		msg.SetEdns0(EDNS_UDP_SIZE, opt.Do())
+		if opt.Version() != 0 {
+			b.Log.WithField("src", w.RemoteAddr().String()).Infof("DNS query received, unsupported EDNS version %v", opt.Version())
+			msg.Rcode = pkgdns.RcodeBadVers
+			w.WriteMsg(&msg)
+			return
+		}
+		// within the payload size of the server too, against fragmentation
+		if network != "tcp" && opt.UDPSize() > uint16(size) {
+			size = int(opt.UDPSize())
+			if size > int(EDNS_UDP_SIZE) {
+				size = int(EDNS_UDP_SIZE)
+			}
+		}
+	}
+	msg.RecursionAvailable = b.Resolver != nil && b.Resolver.Enabled()
+	var forwarder *Forwarder
+	if msg.RecursionAvailable && r.RecursionDesired && len(r.Question) == 1 && !b.Authoritative(r.Question[0].Name) {
+		forwarder = b.Resolver.Match(r.Question[0].Name)
+	}
 	for _, q := range r.Question {
 		log := b.Log.WithFields(logrus.Fields{"src": w.RemoteAddr().String(), "type": q.Qtype, "domain": q.Name})
-		switch q.Qtype {
-		case pkgdns.TypeA:
-			msg.Authoritative = true
-			domain := q.Name
-			record := b.Search(domain, "A")
-			if record != nil {
-				log.Info("DNS query received, found records")
-				ttl := uint32(60)
-				if record.TTL != 0 {
-					ttl = uint32(record.TTL)
-				}
-				for _, addr := range record.GetAddr() {
-					msg.Answer = append(msg.Answer, &pkgdns.A{
-						Hdr: pkgdns.RR_Header{Name: domain, Rrtype: pkgdns.TypeA, Class: pkgdns.ClassINET, Ttl: ttl},
-						A:   net.ParseIP(addr),
-					})
-				}
-				b.Request.With(prometheus.Labels{"resolve": "success"}).Inc()
-			} else {
-				log.Info("DNS query received, no records")
-				b.Request.With(prometheus.Labels{"resolve": "fail"}).Inc()
-			}
-		case pkgdns.TypeAAAA:
-			msg.Authoritative = true
-			domain := q.Name
-			record := b.Search(domain, "AAAA")
-			if record != nil {
-				log.Info("DNS query received, found records")
-				ttl := uint32(60)
-				if record.TTL != 0 {
-					ttl = uint32(record.TTL)
-				}
-				for _, addr := range record.GetAddr() {
-					msg.Answer = append(msg.Answer, &pkgdns.AAAA{
-						Hdr:  pkgdns.RR_Header{Name: domain, Rrtype: pkgdns.TypeAAAA, Class: pkgdns.ClassINET, Ttl: ttl},
-						AAAA: net.ParseIP(addr),
-					})
-				}
-				b.Request.With(prometheus.Labels{"resolve": "success"}).Inc()
-			} else {
-				log.Info("DNS query received, no records")
-				b.Request.With(prometheus.Labels{"resolve": "fail"}).Inc()
-			}
-		default:
-			log.Info("DNS query received, unsupported type")
-			b.Request.With(prometheus.Labels{"resolve": "fail"}).Inc()
+		result := "forwarded"
+		if forwarder == nil {
+			result = b.Answer(&msg, q)
+		} else if resp, err := b.Resolver.Resolve(r, forwarder); err != nil {
+			log.Warnf("unable to forward: %v", err)
+			msg.Rcode, result = pkgdns.RcodeServerFailure, "forward-fail"
+		} else {
+			msg.Rcode, msg.AuthenticatedData = resp.Rcode, resp.AuthenticatedData
+			msg.Answer, msg.Ns = resp.Answer, resp.Ns
+			// the OPT record of the server goes last
+			msg.Extra = append(resp.Extra, msg.Extra...)
 		}
+		log.WithField("rcode", pkgdns.RcodeToString[msg.Rcode]).Infof("DNS query received, %s", result)
+		b.Request.With(prometheus.Labels{"resolve": result}).Inc()
+	}
+	msg.Truncate(size)
+	if msg.Truncated {
+		b.Truncated.With(prometheus.Labels{"net": network}).Inc()
 	}
 	w.WriteMsg(&msg)
 }
 
+// EnvPort reads a port number from the env variable, def if unset
+func EnvPort(name string, def int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	port, err := strconv.Atoi(v)
+	if err != nil || port <= 0 || port > 0xffff {
+		return 0, fmt.Errorf("invalid env variable %s: %s", name, v)
+	}
+	return port, nil
+}
+
 func main() {
 	hostname, err := os.Hostname()
 	if err != nil {
@@ -194,45 +286,88 @@ func main() {
 	}
 	defer depot.Cancel()
 
+	cacheSize := DEFAULT_CACHE_SIZE
+	if v := os.Getenv("DNS_CACHE_SIZE"); v != "" {
+		if cacheSize, err = strconv.Atoi(v); err != nil {
+			log.Fatalf("invalid env variable DNS_CACHE_SIZE: %s", v)
+		}
+	}
+
 	reg := prometheus.NewRegistry()
-	base := BaseDNS{&sync.RWMutex{}, &dns.Zone{}, NewMetrics(reg), log}
+	base := BaseDNS{Locker: &sync.RWMutex{}, Zone: &dns.Zone{}, Metrics: NewMetrics(reg), Log: log}
+	base.Resolver = NewResolver(reg, cacheSize, log)
+	go base.Resolver.Check(UPSTREAM_CHECK_INTERVAL)
 
 	if current == nil {
 		log.Warnf("server not ready, zone data not available")
 	} else {
-		if err := proto.Unmarshal(current.Value, base.Zone); err != nil {
-			log.Fatal(err)
+		zone := &dns.Zone{}
+		if err := proto.Unmarshal(current.Value, zone); err != nil {
+			log.Errorf("invalid zone data %v, server not ready", err)
+		} else if err := zone.Validate(); err != nil {
+			log.Errorf("refused zone data: %v, server not ready", err)
+		} else {
+			base.Update(zone)
+			READY = true
+			base.AuthZone.Set(base.RecordCount())
+			fmt.Println("Current " + base.String())
 		}
-		READY = true
-		base.AuthZone.Set(base.RecordCount())
-		fmt.Println("Current " + base.String())
 	}
 
 	go func() {
 		log.Infof("start zone watcher %s/zone", ETCD_IaC_DNS)
 		for wresp := range zoneCH {
-			ev := wresp.Events[0]
-			if err := proto.Unmarshal(ev.Kv.Value, base.Zone); err != nil {
+			if err := wresp.Err(); err != nil {
+				log.Errorf("zone watch failed: %v", err)
+				continue
+			}
+			if len(wresp.Events) == 0 {
+				continue
+			}
+			ev := wresp.Events[len(wresp.Events)-1]
+			if ev.Type == etcd.EventTypeDelete {
+				log.Warn("zone data deleted, zone kept")
+				continue
+			}
+			zone := &dns.Zone{}
+			if err := proto.Unmarshal(ev.Kv.Value, zone); err != nil {
 				log.Errorf("received invalid zone data %v", err)
 				continue
 			}
+			if err := zone.Validate(); err != nil {
+				log.Errorf("refused zone data: %v", err)
+				continue
+			}
+			base.Update(zone)
 			fmt.Println("New " + base.String())
 
 			READY = true
 			base.AuthZone.Set(base.RecordCount())
 		}
+		log.Warn("zone watcher stopped")
 	}()
 
-	go func() {
-		srv := &pkgdns.Server{
-			Addr:    ":53",
-			Net:     "udp",
-			Handler: &base,
-		}
-		log.Infof("start DNS listener")
-		log.Fatal(srv.ListenAndServe())
-	}()
+	dnsPort, err := EnvPort("DNS_PORT", 53)
+	if err != nil {
+		log.Fatal(err)
+	}
+	metricsPort, err := EnvPort("METRICS_PORT", 2112)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// clients retry over TCP the responses truncated over UDP
+	for _, network := range []string{"udp", "tcp"} {
+		go func(network string) {
+			srv := &pkgdns.Server{
+				Addr:    net.JoinHostPort(DNS_ADDR, strconv.Itoa(dnsPort)),
+				Net:     network,
+				Handler: &base,
+			}
+			log.Infof("start DNS %s listener %s", network, srv.Addr)
+			log.Fatal(srv.ListenAndServe())
+		}(network)
+	}
 
 	http.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg}))
-	log.Fatal(http.ListenAndServe(":2112", nil))
+	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%v", metricsPort), nil))
 }
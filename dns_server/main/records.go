@@ -0,0 +1,140 @@
+package main
+
+import (
+	"net"
+
+	pkgdns "github.com/miekg/dns"
+
+	"github.com/polarbroadband/rp1/proto/dns"
+)
+
+var (
+	DEFAULT_TTL = uint32(60)
+	// longest CNAME chain followed inside the zone
+	MAX_CNAME_CHAIN = 8
+	// longest character-string of a TXT record
+	MAX_TXT_STRING = 255
+)
+
+// RRs converts the zone record of the type into the resource records of the
+// name
+func RRs(name string, rrtype uint16, r *dns.Record) []pkgdns.RR {
+	ttl := DEFAULT_TTL
+	if r.GetTTL() != 0 {
+		ttl = uint32(r.GetTTL())
+	}
+	hdr := func() pkgdns.RR_Header {
+		return pkgdns.RR_Header{Name: name, Rrtype: rrtype, Class: pkgdns.ClassINET, Ttl: ttl}
+	}
+	rrs := []pkgdns.RR{}
+	switch rrtype {
+	case pkgdns.TypeA:
+		for _, addr := range r.GetAddr() {
+			rrs = append(rrs, &pkgdns.A{Hdr: hdr(), A: net.ParseIP(addr)})
+		}
+	case pkgdns.TypeAAAA:
+		for _, addr := range r.GetAddr() {
+			rrs = append(rrs, &pkgdns.AAAA{Hdr: hdr(), AAAA: net.ParseIP(addr)})
+		}
+	case pkgdns.TypeCNAME:
+		rrs = append(rrs, &pkgdns.CNAME{Hdr: hdr(), Target: pkgdns.Fqdn(r.GetTarget())})
+	case pkgdns.TypeNS:
+		for _, h := range r.GetHosts() {
+			rrs = append(rrs, &pkgdns.NS{Hdr: hdr(), Ns: pkgdns.Fqdn(h)})
+		}
+	case pkgdns.TypePTR:
+		for _, h := range r.GetHosts() {
+			rrs = append(rrs, &pkgdns.PTR{Hdr: hdr(), Ptr: pkgdns.Fqdn(h)})
+		}
+	case pkgdns.TypeMX:
+		for _, mx := range r.GetMX() {
+			rrs = append(rrs, &pkgdns.MX{Hdr: hdr(), Preference: uint16(mx.GetPreference()), Mx: pkgdns.Fqdn(mx.GetExchange())})
+		}
+	case pkgdns.TypeTXT:
+		for _, t := range r.GetText() {
+			rrs = append(rrs, &pkgdns.TXT{Hdr: hdr(), Txt: splitTxt(t)})
+		}
+	case pkgdns.TypeSRV:
+		for _, s := range r.GetSRV() {
+			rrs = append(rrs, &pkgdns.SRV{Hdr: hdr(), Priority: uint16(s.GetPriority()), Weight: uint16(s.GetWeight()), Port: uint16(s.GetPort()), Target: pkgdns.Fqdn(s.GetTarget())})
+		}
+	case pkgdns.TypeSOA:
+		if soa := r.GetSOA(); soa != nil {
+			rrs = append(rrs, &pkgdns.SOA{
+				Hdr:     hdr(),
+				Ns:      pkgdns.Fqdn(soa.GetMName()),
+				Mbox:    pkgdns.Fqdn(soa.GetRName()),
+				Serial:  soa.GetSerial(),
+				Refresh: soa.GetRefresh(),
+				Retry:   soa.GetRetry(),
+				Expire:  soa.GetExpire(),
+				Minttl:  soa.GetMinimum(),
+			})
+		}
+	case pkgdns.TypeCAA:
+		for _, c := range r.GetCAA() {
+			rrs = append(rrs, &pkgdns.CAA{Hdr: hdr(), Flag: uint8(c.GetFlag()), Tag: c.GetTag(), Value: c.GetValue()})
+		}
+	}
+	return rrs
+}
+
+// splitTxt cuts a TXT string into character-strings
+func splitTxt(s string) []string {
+	parts := []string{}
+	for len(s) > MAX_TXT_STRING {
+		parts = append(parts, s[:MAX_TXT_STRING])
+		s = s[MAX_TXT_STRING:]
+	}
+	return append(parts, s)
+}
+
+// Answer answers the question out of the zone the name falls under,
+// following the CNAMEs of the name as long as their targets are in the
+// zones, and returns the outcome: success, nodata, nxdomain or refused.
+// Negative answers carry the SOA of the zone in the authority section as
+// RFC 2308 defines it
+func (b *BaseDNS) Answer(msg *pkgdns.Msg, q pkgdns.Question) string {
+	b.Locker.RLock()
+	defer b.Locker.RUnlock()
+	name, qtype := q.Name, q.Qtype
+	origin := dns.Origin(b.GetOrigins(), name)
+	if origin == "" {
+		msg.Rcode = pkgdns.RcodeRefused
+		return "refused"
+	}
+	msg.Authoritative = true
+	seen := map[string]bool{}
+	for i := 0; i <= MAX_CNAME_CHAIN && !seen[dns.Canonical(name)]; i++ {
+		seen[dns.Canonical(name)] = true
+		if r := b.search(name, pkgdns.TypeToString[qtype]); r != nil {
+			msg.Answer = append(msg.Answer, RRs(name, qtype, r)...)
+			return "success"
+		}
+		cname := b.search(name, "CNAME")
+		if cname == nil {
+			break
+		}
+		msg.Answer = append(msg.Answer, RRs(name, pkgdns.TypeCNAME, cname)...)
+		name = pkgdns.Fqdn(cname.GetTarget())
+		// the resolver carries on with targets out of the zones
+		if origin = dns.Origin(b.GetOrigins(), name); origin == "" {
+			return "success"
+		}
+	}
+
+	if soa := b.search(origin, "SOA"); soa != nil {
+		for _, rr := range RRs(pkgdns.Fqdn(origin), pkgdns.TypeSOA, soa) {
+			// negative answers are cached for the lesser of the SOA TTL and minimum
+			if min := soa.GetSOA().GetMinimum(); rr.Header().Ttl > min {
+				rr.Header().Ttl = min
+			}
+			msg.Ns = append(msg.Ns, rr)
+		}
+	}
+	if b.exists(name) {
+		return "nodata"
+	}
+	msg.Rcode = pkgdns.RcodeNameError
+	return "nxdomain"
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	pkgdns "github.com/miekg/dns"
+	"github.com/sirupsen/logrus"
+
+	"github.com/polarbroadband/rp1/proto/dns"
+)
+
+func testZone() *dns.Zone {
+	a := func(addr ...string) *dns.Category {
+		return &dns.Category{Type: map[string]*dns.Record{"A": {Addr: addr}}}
+	}
+	cname := func(target string) *dns.Category {
+		return &dns.Category{Type: map[string]*dns.Record{"CNAME": {Target: target}}}
+	}
+	return &dns.Zone{
+		Origins: []string{"example.com"},
+		Records: map[string]*dns.Category{
+			"example.com": {Type: map[string]*dns.Record{
+				"SOA": {TTL: 3600, SOA: &dns.SOA{MName: "ns1.example.com", RName: "admin.example.com", Serial: 1, Minimum: 300}},
+				"NS":  {Hosts: []string{"ns1.example.com"}},
+			}},
+			"ns1.example.com":   a("192.0.2.53"),
+			"www.example.com":   a("192.0.2.1", "192.0.2.2"),
+			"a.b.example.com":   a("192.0.2.3"),
+			"alias.example.com": cname("www.example.com"),
+			"chain.example.com": cname("alias.example.com"),
+			"out.example.com":   cname("www.example.org"),
+			"dead.example.com":  cname("nowhere.example.com"),
+			"mail.example.com": {Type: map[string]*dns.Record{
+				"MX":  {MX: []*dns.MX{{Preference: 10, Exchange: "mx.example.com"}}},
+				"TXT": {Text: []string{"v=spf1 -all"}},
+			}},
+			"*.dyn.example.com":    a("192.0.2.9"),
+			"host.dyn.example.com": {Type: map[string]*dns.Record{"TXT": {Text: []string{"static"}}}},
+		},
+	}
+}
+
+func newTestDNS(t *testing.T) *BaseDNS {
+	t.Helper()
+	z := testZone()
+	if err := z.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	b := &BaseDNS{Locker: &sync.RWMutex{}, Log: logrus.NewEntry(l)}
+	b.Update(z)
+	return b
+}
+
+func TestRRs(t *testing.T) {
+	long := strings.Repeat("a", MAX_TXT_STRING) + "b"
+	for _, tc := range []struct {
+		rrtype uint16
+		r      *dns.Record
+		want   []string
+	}{
+		{pkgdns.TypeA, &dns.Record{Addr: []string{"192.0.2.1"}}, []string{"x.example.com.\t60\tIN\tA\t192.0.2.1"}},
+		{pkgdns.TypeAAAA, &dns.Record{Addr: []string{"2001:db8::1"}, TTL: 30}, []string{"x.example.com.\t30\tIN\tAAAA\t2001:db8::1"}},
+		{pkgdns.TypeCNAME, &dns.Record{Target: "www.example.com"}, []string{"x.example.com.\t60\tIN\tCNAME\twww.example.com."}},
+		{pkgdns.TypeNS, &dns.Record{Hosts: []string{"ns1.example.com", "ns2.example.com."}}, []string{"x.example.com.\t60\tIN\tNS\tns1.example.com.", "x.example.com.\t60\tIN\tNS\tns2.example.com."}},
+		{pkgdns.TypePTR, &dns.Record{Hosts: []string{"host.example.com"}}, []string{"x.example.com.\t60\tIN\tPTR\thost.example.com."}},
+		{pkgdns.TypeMX, &dns.Record{MX: []*dns.MX{{Preference: 10, Exchange: "mx.example.com"}}}, []string{"x.example.com.\t60\tIN\tMX\t10 mx.example.com."}},
+		{pkgdns.TypeTXT, &dns.Record{Text: []string{"hello world"}}, []string{"x.example.com.\t60\tIN\tTXT\t\"hello world\""}},
+		{pkgdns.TypeTXT, &dns.Record{Text: []string{long}}, []string{"x.example.com.\t60\tIN\tTXT\t\"" + long[:MAX_TXT_STRING] + "\" \"b\""}},
+		{pkgdns.TypeSRV, &dns.Record{SRV: []*dns.SRV{{Priority: 1, Weight: 2, Port: 5060, Target: "sip.example.com"}}}, []string{"x.example.com.\t60\tIN\tSRV\t1 2 5060 sip.example.com."}},
+		{pkgdns.TypeSOA, &dns.Record{SOA: &dns.SOA{MName: "ns1.example.com", RName: "admin.example.com", Serial: 7, Refresh: 1, Retry: 2, Expire: 3, Minimum: 4}}, []string{"x.example.com.\t60\tIN\tSOA\tns1.example.com. admin.example.com. 7 1 2 3 4"}},
+		{pkgdns.TypeCAA, &dns.Record{CAA: []*dns.CAA{{Tag: "issue", Value: "ca.example.net"}}}, []string{"x.example.com.\t60\tIN\tCAA\t0 issue \"ca.example.net\""}},
+	} {
+		t.Run(pkgdns.TypeToString[tc.rrtype], func(t *testing.T) {
+			rrs := RRs("x.example.com.", tc.rrtype, tc.r)
+			if len(rrs) != len(tc.want) {
+				t.Fatalf("got %v, want %v", rrs, tc.want)
+			}
+			for i, rr := range rrs {
+				if rr.String() != tc.want[i] {
+					t.Errorf("got %q, want %q", rr.String(), tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAnswer(t *testing.T) {
+	b := newTestDNS(t)
+	for _, tc := range []struct {
+		name   string
+		qtype  uint16
+		result string
+		rcode  int
+		// owner names and types of the answer section
+		answer []string
+	}{
+		{"www.example.com.", pkgdns.TypeA, "success", pkgdns.RcodeSuccess, []string{"www.example.com. A", "www.example.com. A"}},
+		{"WWW.Example.COM.", pkgdns.TypeA, "success", pkgdns.RcodeSuccess, []string{"WWW.Example.COM. A", "WWW.Example.COM. A"}},
+		{"mail.example.com.", pkgdns.TypeMX, "success", pkgdns.RcodeSuccess, []string{"mail.example.com. MX"}},
+		{"example.com.", pkgdns.TypeSOA, "success", pkgdns.RcodeSuccess, []string{"example.com. SOA"}},
+		{"alias.example.com.", pkgdns.TypeA, "success", pkgdns.RcodeSuccess, []string{"alias.example.com. CNAME", "www.example.com. A", "www.example.com. A"}},
+		{"chain.example.com.", pkgdns.TypeA, "success", pkgdns.RcodeSuccess, []string{"chain.example.com. CNAME", "alias.example.com. CNAME", "www.example.com. A", "www.example.com. A"}},
+		{"alias.example.com.", pkgdns.TypeCNAME, "success", pkgdns.RcodeSuccess, []string{"alias.example.com. CNAME"}},
+		{"out.example.com.", pkgdns.TypeA, "success", pkgdns.RcodeSuccess, []string{"out.example.com. CNAME"}},
+		{"dead.example.com.", pkgdns.TypeA, "nxdomain", pkgdns.RcodeNameError, []string{"dead.example.com. CNAME"}},
+
+		{"www.example.com.", pkgdns.TypeAAAA, "nodata", pkgdns.RcodeSuccess, nil},
+		{"mail.example.com.", pkgdns.TypeA, "nodata", pkgdns.RcodeSuccess, nil},
+		{"b.example.com.", pkgdns.TypeA, "nodata", pkgdns.RcodeSuccess, nil},
+		{"nope.example.com.", pkgdns.TypeA, "nxdomain", pkgdns.RcodeNameError, nil},
+		{"x.www.example.com.", pkgdns.TypeA, "nxdomain", pkgdns.RcodeNameError, nil},
+
+		{"x.dyn.example.com.", pkgdns.TypeA, "success", pkgdns.RcodeSuccess, []string{"x.dyn.example.com. A"}},
+		{"a.x.dyn.example.com.", pkgdns.TypeA, "success", pkgdns.RcodeSuccess, []string{"a.x.dyn.example.com. A"}},
+		{"x.dyn.example.com.", pkgdns.TypeAAAA, "nodata", pkgdns.RcodeSuccess, nil},
+		{"host.dyn.example.com.", pkgdns.TypeA, "nodata", pkgdns.RcodeSuccess, nil},
+		{"x.host.dyn.example.com.", pkgdns.TypeA, "nxdomain", pkgdns.RcodeNameError, nil},
+		{"dyn.example.com.", pkgdns.TypeA, "nodata", pkgdns.RcodeSuccess, nil},
+
+		{"www.example.org.", pkgdns.TypeA, "refused", pkgdns.RcodeRefused, nil},
+		{"example.com.org.", pkgdns.TypeA, "refused", pkgdns.RcodeRefused, nil},
+	} {
+		t.Run(tc.name+pkgdns.TypeToString[tc.qtype], func(t *testing.T) {
+			msg := &pkgdns.Msg{}
+			result := b.Answer(msg, pkgdns.Question{Name: tc.name, Qtype: tc.qtype, Qclass: pkgdns.ClassINET})
+			if result != tc.result || msg.Rcode != tc.rcode {
+				t.Errorf("%s %s, want %s %s", result, pkgdns.RcodeToString[msg.Rcode], tc.result, pkgdns.RcodeToString[tc.rcode])
+			}
+			if len(msg.Answer) != len(tc.answer) {
+				t.Fatalf("answer %v, want %v", msg.Answer, tc.answer)
+			}
+			for i, rr := range msg.Answer {
+				if got := rr.Header().Name + " " + pkgdns.TypeToString[rr.Header().Rrtype]; got != tc.answer[i] {
+					t.Errorf("answer %v %s, want %s", i, got, tc.answer[i])
+				}
+			}
+			if tc.result == "refused" {
+				if msg.Authoritative || len(msg.Ns) > 0 {
+					t.Errorf("refused answer authoritative %v with authority %v", msg.Authoritative, msg.Ns)
+				}
+				return
+			}
+			if !msg.Authoritative {
+				t.Error("not authoritative")
+			}
+			// negative answers carry the SOA with the negative caching TTL
+			if tc.result == "nodata" || tc.result == "nxdomain" {
+				if len(msg.Ns) != 1 {
+					t.Fatalf("authority %v, want the SOA", msg.Ns)
+				}
+				soa, ok := msg.Ns[0].(*pkgdns.SOA)
+				if !ok || soa.Hdr.Name != "example.com." || soa.Hdr.Ttl != 300 {
+					t.Errorf("authority %v, want the example.com. SOA with TTL 300", msg.Ns[0])
+				}
+			} else if len(msg.Ns) > 0 {
+				t.Errorf("authority %v on success", msg.Ns)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	b := newTestDNS(t)
+	for _, tc := range []struct {
+		name string
+		// type of the records found, empty if none
+		rtype string
+		exist bool
+	}{
+		{"www.example.com.", "A", true},
+		{"Www.Example.Com", "A", true},
+		{"b.example.com.", "", true},
+		{"nope.example.com.", "", false},
+		{"x.dyn.example.com.", "A", true},
+		{"*.dyn.example.com.", "A", true},
+		{"host.dyn.example.com.", "TXT", true},
+		{"x.host.dyn.example.com.", "", false},
+		{"x.b.example.com.", "", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := b.lookup(tc.name)
+			if tc.rtype == "" {
+				if c != nil {
+					t.Errorf("found %v, want none", c)
+				}
+			} else if _, ok := c.GetType()[tc.rtype]; !ok {
+				t.Errorf("found %v, want %s records", c, tc.rtype)
+			}
+			if b.exists(tc.name) != tc.exist {
+				t.Errorf("exists %v, want %v", !tc.exist, tc.exist)
+			}
+		})
+	}
+}
@@ -118,6 +118,18 @@ func (kv *KvDepot) Put(key, val string, lease int64) error {
 	return nil
 }
 
+func (kv *KvDepot) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), kv.OprTimeout)
+	defer cancel()
+	k := kv.Depot + "/" + key
+	if _, err := kv.Conn.Delete(ctx, k); err != nil {
+		e := fmt.Errorf("unable to delete key %s %v", k, err)
+		kv.Log.Error(e)
+		return e
+	}
+	return nil
+}
+
 func (kv *KvDepot) Subscribe(key string) (*KV, etcd.WatchChan, error) {
 	currentKV, err := kv.Get(key)
 	if err != nil {
@@ -129,6 +141,33 @@ func (kv *KvDepot) Subscribe(key string) (*KV, etcd.WatchChan, error) {
 	return currentKV, kv.Conn.Watch(ctx, kv.Depot+"/"+key), nil
 }
 
+// SubscribeDir returns every current key under path and watches the path for
+// changes made after the revision of the read, even when the path is empty
+func (kv *KvDepot) SubscribeDir(path string) ([]*KV, etcd.WatchChan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), kv.OprTimeout)
+	defer cancel()
+	k := kv.Depot + "/" + path
+	resp, err := kv.Conn.Get(ctx, k, etcd.WithPrefix())
+	if err != nil {
+		e := fmt.Errorf("unable to get etcd prefix <<%s>> %v", k, err)
+		kv.Log.Error(e)
+		return nil, nil, e
+	}
+	currentKVs := []*KV{}
+	for _, d := range resp.Kvs {
+		currentKVs = append(currentKVs, &KV{
+			Key:      string(d.Key),
+			Value:    d.Value,
+			Lease:    d.Lease,
+			Revision: resp.Header.Revision,
+		})
+	}
+
+	wctx, wcancel := context.WithCancel(context.Background())
+	kv.Cancel = wcancel
+	return currentKVs, kv.Conn.Watch(wctx, k, etcd.WithPrefix(), etcd.WithRev(resp.Header.Revision+1)), nil
+}
+
 func (kv *KvDepot) KeepAlive(key string) error {
 
 	return nil
@@ -15,6 +15,7 @@ import (
 
 	"github.com/polarbroadband/rp1/etcdlib"
 	"github.com/polarbroadband/rp1/gitlib"
+	"github.com/polarbroadband/rp1/proto/dhcp"
 	"github.com/polarbroadband/rp1/proto/dns"
 	"gopkg.in/yaml.v3"
 )
@@ -37,6 +38,10 @@ type DnsZone struct {
 	Spec *dns.Zone `json:"Spec" yaml:"spec"`
 }
 
+type DhcpConfig struct {
+	Spec *dhcp.Config `json:"Spec" yaml:"spec"`
+}
+
 // healtz response k8s health check probe
 func (api *Gateway) healtz(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -71,21 +76,21 @@ func (api *Gateway) WebhookV1Push(w http.ResponseWriter, r *http.Request) {
 	}
 
 	commit := gitlib.GitLabCommit{
-		GitLab: api.GitLab,
-		Repo:   event.Repo.ID,
-		Commit: event.Commit,
-		Log: api.Log.WithFields(logrus.Fields{
-			"git":    r.Header.Get("X-Gitlab-Instance"),
-			"repo":   event.Repo.Name,
-			"commit": event.Commit,
-		}),
+		GitLab:               api.GitLab,
+		GitLabCheckoutCommit: &gitlib.GitLabCheckoutCommit{SHA: event.Commit},
+		Repo:                 event.Repo.ID,
 	}
+	log := api.Log.WithFields(logrus.Fields{
+		"git":    r.Header.Get("X-Gitlab-Instance"),
+		"repo":   event.Repo.Name,
+		"commit": event.Commit,
+	})
 
-	commit.Log.Info("webhook push event")
+	log.Info("webhook push event")
 
 	switch strings.ToLower(event.Repo.Name) {
 	case "dns":
-		blobs, err := commit.GetRepoRawFiles(regexp.MustCompile(IaC_PATTERN_DNS))
+		blobs, err := commit.GetRepoRawFiles(regexp.MustCompile(IaC_PATTERN_DNS), false)
 		if err != nil {
 			api.Error(w, http.StatusInternalServerError, fmt.Sprintf("unable to get content %v", err))
 			return
@@ -95,7 +100,7 @@ func (api *Gateway) WebhookV1Push(w http.ResponseWriter, r *http.Request) {
 			Records: make(map[string]*dns.Category),
 		}
 		for _, b := range blobs {
-			commit.Log.Infof("processing file: %s", b.Path)
+			log.Infof("processing file: %s", b.Path)
 
 			var meta MetaIaC
 			err = yaml.Unmarshal(b.Content, &meta)
@@ -123,10 +128,16 @@ func (api *Gateway) WebhookV1Push(w http.ResponseWriter, r *http.Request) {
 
 				//pretty.Printf("\n--- DNS STRUCT ---\n%# v\n\n", data.Spec)
 				maps.Copy(commitData.Records, data.Spec.Records)
+				commitData.Origins = append(commitData.Origins, data.Spec.GetOrigins()...)
+				commitData.Forwarders = append(commitData.Forwarders, data.Spec.GetForwarders()...)
 			}
 			// v2 ... model
 		}
 
+		if err := commitData.Validate(); err != nil {
+			api.Error(w, http.StatusInternalServerError, fmt.Sprintf("invalid dns data %v", err))
+			return
+		}
 		out, err := proto.Marshal(commitData)
 		if err != nil {
 			api.Error(w, http.StatusInternalServerError, fmt.Sprintf("unable to serialize data %v", err))
@@ -139,14 +150,65 @@ func (api *Gateway) WebhookV1Push(w http.ResponseWriter, r *http.Request) {
 		}
 
 	case "dhcp":
-		blobs, err := commit.GetRepoRawFiles(regexp.MustCompile(IaC_PATTERN_DHCP))
+		blobs, err := commit.GetRepoRawFiles(regexp.MustCompile(IaC_PATTERN_DHCP), false)
 		if err != nil {
 			api.Error(w, http.StatusInternalServerError, fmt.Sprintf("unable to get content %v", err))
 			return
 		}
-		//pretty.Printf("\n--- Selected BLOB STRUCT ---\n%# v\n\n", blobs)
+		commitData := &dhcp.Config{
+			Commit:   event.Commit,
+			Networks: make(map[string]*dhcp.Network),
+			Version:  dhcp.Version,
+		}
 		for _, b := range blobs {
-			commit.Log.Infof("file: %s +++++++++ RAW: <<%v>>", b.Path, b.Content)
+			log.Infof("processing file: %s", b.Path)
+
+			var meta MetaIaC
+			err = yaml.Unmarshal(b.Content, &meta)
+
+			if err != nil {
+				api.Error(w, http.StatusInternalServerError, fmt.Sprintf("unable to parse meta of %s: %v", b.Path, err))
+				return
+			}
+
+			if meta.Kind != "DHCP" {
+				api.Error(w, http.StatusInternalServerError, fmt.Sprintf("unable to parse data of %s: invalid service kind", b.Path))
+				return
+			}
+			if meta.ApiVersion == "service/v1" {
+				// v1 DHCP data model
+				var data DhcpConfig
+				err = yaml.Unmarshal(b.Content, &data)
+
+				if err != nil {
+					api.Error(w, http.StatusInternalServerError, fmt.Sprintf("unable to parse data of %s: %v", b.Path, err))
+					return
+				}
+
+				for name, n := range data.Spec.GetNetworks() {
+					if _, exist := commitData.Networks[name]; exist {
+						api.Error(w, http.StatusInternalServerError, fmt.Sprintf("unable to parse data of %s: duplicated network %s", b.Path, name))
+						return
+					}
+					commitData.Networks[name] = n
+				}
+			}
+			// v2 ... model
+		}
+
+		if err := commitData.Validate(); err != nil {
+			api.Error(w, http.StatusInternalServerError, fmt.Sprintf("invalid dhcp data %v", err))
+			return
+		}
+		out, err := proto.Marshal(commitData)
+		if err != nil {
+			api.Error(w, http.StatusInternalServerError, fmt.Sprintf("unable to serialize data %v", err))
+			return
+		}
+		depot := etcdlib.NewKvDepot(ETCD_IaC_DHCP, api.Client, api.Log)
+		if err = depot.Put("network", string(out), 0); err != nil {
+			api.Error(w, http.StatusInternalServerError, fmt.Sprintf("unable to publish data %v", err))
+			return
 		}
 
 	default:
@@ -0,0 +1,1016 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        v3.12.4
+// source: dhcp.proto
+
+package dhcp
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Config is the DHCP data published by gitops, Version is the apiVersion of
+// the IaC model it was built from
+type Config struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Commit   string              `protobuf:"bytes,1,opt,name=Commit,proto3" json:"Commit,omitempty" yaml:"commit"`
+	Networks map[string]*Network `protobuf:"bytes,2,rep,name=Networks,proto3" json:"Networks,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3" yaml:"networks"`
+	Version  string              `protobuf:"bytes,3,opt,name=Version,proto3" json:"Version,omitempty" yaml:"version"`
+}
+
+func (x *Config) Reset() {
+	*x = Config{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dhcp_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Config) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Config) ProtoMessage() {}
+
+func (x *Config) ProtoReflect() protoreflect.Message {
+	mi := &file_dhcp_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Config.ProtoReflect.Descriptor instead.
+func (*Config) Descriptor() ([]byte, []int) {
+	return file_dhcp_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Config) GetCommit() string {
+	if x != nil {
+		return x.Commit
+	}
+	return ""
+}
+
+func (x *Config) GetNetworks() map[string]*Network {
+	if x != nil {
+		return x.Networks
+	}
+	return nil
+}
+
+func (x *Config) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type Network struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	LinkAddr   string  `protobuf:"bytes,1,opt,name=LinkAddr,proto3" json:"LinkAddr,omitempty" yaml:"linkAddr"`
+	Gateway    string  `protobuf:"bytes,2,opt,name=Gateway,proto3" json:"Gateway,omitempty" yaml:"gateway"`
+	Prefix     string  `protobuf:"bytes,3,opt,name=Prefix,proto3" json:"Prefix,omitempty" yaml:"prefix"`
+	SubnetMask string  `protobuf:"bytes,4,opt,name=SubnetMask,proto3" json:"SubnetMask,omitempty" yaml:"subnetMask"`
+	Redirect   string  `protobuf:"bytes,5,opt,name=Redirect,proto3" json:"Redirect,omitempty" yaml:"redirect"`
+	TTL        int64   `protobuf:"varint,6,opt,name=TTL,proto3" json:"TTL,omitempty" yaml:"ttl"`
+	Pools      []*Pool `protobuf:"bytes,7,rep,name=Pools,proto3" json:"Pools,omitempty" yaml:"pools"`
+	// address lifetimes in seconds
+	ValidLifetime     int64 `protobuf:"varint,8,opt,name=ValidLifetime,proto3" json:"ValidLifetime,omitempty" yaml:"validLifetime"`
+	PreferredLifetime int64 `protobuf:"varint,9,opt,name=PreferredLifetime,proto3" json:"PreferredLifetime,omitempty" yaml:"preferredLifetime"`
+	// length of the prefixes delegated out of Prefix, 0 disables IA_PD
+	DelegatedLength int32          `protobuf:"varint,10,opt,name=DelegatedLength,proto3" json:"DelegatedLength,omitempty" yaml:"delegatedLength"`
+	Reservations    []*Reservation `protobuf:"bytes,11,rep,name=Reservations,proto3" json:"Reservations,omitempty" yaml:"reservations"`
+	Options         *Options       `protobuf:"bytes,12,opt,name=Options,proto3" json:"Options,omitempty" yaml:"options"`
+	// hex encoded interface-ids of the relay agents serving the network,
+	// selects the network when the relay link-address does not
+	InterfaceIDs []string `protobuf:"bytes,13,rep,name=InterfaceIDs,proto3" json:"InterfaceIDs,omitempty" yaml:"interfaceIDs"`
+	// commit bindings on a Solicit with the Rapid Commit option
+	RapidCommit bool `protobuf:"varint,14,opt,name=RapidCommit,proto3" json:"RapidCommit,omitempty" yaml:"rapidCommit"`
+}
+
+func (x *Network) Reset() {
+	*x = Network{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dhcp_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Network) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Network) ProtoMessage() {}
+
+func (x *Network) ProtoReflect() protoreflect.Message {
+	mi := &file_dhcp_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Network.ProtoReflect.Descriptor instead.
+func (*Network) Descriptor() ([]byte, []int) {
+	return file_dhcp_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Network) GetLinkAddr() string {
+	if x != nil {
+		return x.LinkAddr
+	}
+	return ""
+}
+
+func (x *Network) GetGateway() string {
+	if x != nil {
+		return x.Gateway
+	}
+	return ""
+}
+
+func (x *Network) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *Network) GetSubnetMask() string {
+	if x != nil {
+		return x.SubnetMask
+	}
+	return ""
+}
+
+func (x *Network) GetRedirect() string {
+	if x != nil {
+		return x.Redirect
+	}
+	return ""
+}
+
+func (x *Network) GetTTL() int64 {
+	if x != nil {
+		return x.TTL
+	}
+	return 0
+}
+
+func (x *Network) GetPools() []*Pool {
+	if x != nil {
+		return x.Pools
+	}
+	return nil
+}
+
+func (x *Network) GetValidLifetime() int64 {
+	if x != nil {
+		return x.ValidLifetime
+	}
+	return 0
+}
+
+func (x *Network) GetPreferredLifetime() int64 {
+	if x != nil {
+		return x.PreferredLifetime
+	}
+	return 0
+}
+
+func (x *Network) GetDelegatedLength() int32 {
+	if x != nil {
+		return x.DelegatedLength
+	}
+	return 0
+}
+
+func (x *Network) GetReservations() []*Reservation {
+	if x != nil {
+		return x.Reservations
+	}
+	return nil
+}
+
+func (x *Network) GetOptions() *Options {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *Network) GetInterfaceIDs() []string {
+	if x != nil {
+		return x.InterfaceIDs
+	}
+	return nil
+}
+
+func (x *Network) GetRapidCommit() bool {
+	if x != nil {
+		return x.RapidCommit
+	}
+	return false
+}
+
+type Pool struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Begin string `protobuf:"bytes,1,opt,name=Begin,proto3" json:"Begin,omitempty" yaml:"begin"`
+	End   string `protobuf:"bytes,2,opt,name=End,proto3" json:"End,omitempty" yaml:"end"`
+}
+
+func (x *Pool) Reset() {
+	*x = Pool{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dhcp_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Pool) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pool) ProtoMessage() {}
+
+func (x *Pool) ProtoReflect() protoreflect.Message {
+	mi := &file_dhcp_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pool.ProtoReflect.Descriptor instead.
+func (*Pool) Descriptor() ([]byte, []int) {
+	return file_dhcp_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Pool) GetBegin() string {
+	if x != nil {
+		return x.Begin
+	}
+	return ""
+}
+
+func (x *Pool) GetEnd() string {
+	if x != nil {
+		return x.End
+	}
+	return ""
+}
+
+// Reservation pins an address and/or a delegated prefix to one host, matched
+// by any of the set identifiers
+type Reservation struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name string `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty" yaml:"name"`
+	// hex encoded client DUID
+	DUID string `protobuf:"bytes,2,opt,name=DUID,proto3" json:"DUID,omitempty" yaml:"duid"`
+	// link-layer address of a DUID-LL or DUID-LLT client
+	MAC string `protobuf:"bytes,3,opt,name=MAC,proto3" json:"MAC,omitempty" yaml:"mac"`
+	// relay supplied identifiers, hex encoded
+	InterfaceID string   `protobuf:"bytes,4,opt,name=InterfaceID,proto3" json:"InterfaceID,omitempty" yaml:"interfaceID"`
+	RemoteID    string   `protobuf:"bytes,5,opt,name=RemoteID,proto3" json:"RemoteID,omitempty" yaml:"remoteID"`
+	Addr        string   `protobuf:"bytes,6,opt,name=Addr,proto3" json:"Addr,omitempty" yaml:"addr"`
+	Prefix      string   `protobuf:"bytes,7,opt,name=Prefix,proto3" json:"Prefix,omitempty" yaml:"prefix"`
+	Options     *Options `protobuf:"bytes,8,opt,name=Options,proto3" json:"Options,omitempty" yaml:"options"`
+}
+
+func (x *Reservation) Reset() {
+	*x = Reservation{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dhcp_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Reservation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Reservation) ProtoMessage() {}
+
+func (x *Reservation) ProtoReflect() protoreflect.Message {
+	mi := &file_dhcp_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Reservation.ProtoReflect.Descriptor instead.
+func (*Reservation) Descriptor() ([]byte, []int) {
+	return file_dhcp_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Reservation) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Reservation) GetDUID() string {
+	if x != nil {
+		return x.DUID
+	}
+	return ""
+}
+
+func (x *Reservation) GetMAC() string {
+	if x != nil {
+		return x.MAC
+	}
+	return ""
+}
+
+func (x *Reservation) GetInterfaceID() string {
+	if x != nil {
+		return x.InterfaceID
+	}
+	return ""
+}
+
+func (x *Reservation) GetRemoteID() string {
+	if x != nil {
+		return x.RemoteID
+	}
+	return ""
+}
+
+func (x *Reservation) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+func (x *Reservation) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *Reservation) GetOptions() *Options {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+// Options are the configuration options handed to clients, the ones of a
+// reservation override the ones of its network
+type Options struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	DNSServers     []string        `protobuf:"bytes,1,rep,name=DNSServers,proto3" json:"DNSServers,omitempty" yaml:"dnsServers"`
+	DomainList     []string        `protobuf:"bytes,2,rep,name=DomainList,proto3" json:"DomainList,omitempty" yaml:"domainList"`
+	BootFileURL    string          `protobuf:"bytes,3,opt,name=BootFileURL,proto3" json:"BootFileURL,omitempty" yaml:"bootFileURL"`
+	BootFileParams []string        `protobuf:"bytes,4,rep,name=BootFileParams,proto3" json:"BootFileParams,omitempty" yaml:"bootFileParams"`
+	NTPServers     []string        `protobuf:"bytes,5,rep,name=NTPServers,proto3" json:"NTPServers,omitempty" yaml:"ntpServers"`
+	VendorOptions  []*VendorOption `protobuf:"bytes,6,rep,name=VendorOptions,proto3" json:"VendorOptions,omitempty" yaml:"vendorOptions"`
+	RawOptions     []*RawOption    `protobuf:"bytes,7,rep,name=RawOptions,proto3" json:"RawOptions,omitempty" yaml:"rawOptions"`
+}
+
+func (x *Options) Reset() {
+	*x = Options{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dhcp_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Options) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Options) ProtoMessage() {}
+
+func (x *Options) ProtoReflect() protoreflect.Message {
+	mi := &file_dhcp_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Options.ProtoReflect.Descriptor instead.
+func (*Options) Descriptor() ([]byte, []int) {
+	return file_dhcp_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Options) GetDNSServers() []string {
+	if x != nil {
+		return x.DNSServers
+	}
+	return nil
+}
+
+func (x *Options) GetDomainList() []string {
+	if x != nil {
+		return x.DomainList
+	}
+	return nil
+}
+
+func (x *Options) GetBootFileURL() string {
+	if x != nil {
+		return x.BootFileURL
+	}
+	return ""
+}
+
+func (x *Options) GetBootFileParams() []string {
+	if x != nil {
+		return x.BootFileParams
+	}
+	return nil
+}
+
+func (x *Options) GetNTPServers() []string {
+	if x != nil {
+		return x.NTPServers
+	}
+	return nil
+}
+
+func (x *Options) GetVendorOptions() []*VendorOption {
+	if x != nil {
+		return x.VendorOptions
+	}
+	return nil
+}
+
+func (x *Options) GetRawOptions() []*RawOption {
+	if x != nil {
+		return x.RawOptions
+	}
+	return nil
+}
+
+type VendorOption struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	EnterpriseNumber uint32       `protobuf:"varint,1,opt,name=EnterpriseNumber,proto3" json:"EnterpriseNumber,omitempty" yaml:"enterpriseNumber"`
+	Options          []*RawOption `protobuf:"bytes,2,rep,name=Options,proto3" json:"Options,omitempty" yaml:"options"`
+}
+
+func (x *VendorOption) Reset() {
+	*x = VendorOption{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dhcp_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VendorOption) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VendorOption) ProtoMessage() {}
+
+func (x *VendorOption) ProtoReflect() protoreflect.Message {
+	mi := &file_dhcp_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VendorOption.ProtoReflect.Descriptor instead.
+func (*VendorOption) Descriptor() ([]byte, []int) {
+	return file_dhcp_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *VendorOption) GetEnterpriseNumber() uint32 {
+	if x != nil {
+		return x.EnterpriseNumber
+	}
+	return 0
+}
+
+func (x *VendorOption) GetOptions() []*RawOption {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+// RawOption is an option of any code with hex encoded data
+type RawOption struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code uint32 `protobuf:"varint,1,opt,name=Code,proto3" json:"Code,omitempty" yaml:"code"`
+	Data string `protobuf:"bytes,2,opt,name=Data,proto3" json:"Data,omitempty" yaml:"data"`
+}
+
+func (x *RawOption) Reset() {
+	*x = RawOption{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dhcp_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RawOption) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RawOption) ProtoMessage() {}
+
+func (x *RawOption) ProtoReflect() protoreflect.Message {
+	mi := &file_dhcp_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RawOption.ProtoReflect.Descriptor instead.
+func (*RawOption) Descriptor() ([]byte, []int) {
+	return file_dhcp_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RawOption) GetCode() uint32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *RawOption) GetData() string {
+	if x != nil {
+		return x.Data
+	}
+	return ""
+}
+
+type Lease struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Network string `protobuf:"bytes,1,opt,name=Network,proto3" json:"Network,omitempty" yaml:"network"`
+	// hex encoded, DHCPv4 bindings hold the client identifier or hardware
+	// address with IAID "v4"
+	DUID string `protobuf:"bytes,2,opt,name=DUID,proto3" json:"DUID,omitempty" yaml:"duid"`
+	IAID string `protobuf:"bytes,3,opt,name=IAID,proto3" json:"IAID,omitempty" yaml:"iaid"`
+	Addr string `protobuf:"bytes,4,opt,name=Addr,proto3" json:"Addr,omitempty" yaml:"addr"`
+	// set for a delegated prefix
+	PrefixLen int32 `protobuf:"varint,9,opt,name=PrefixLen,proto3" json:"PrefixLen,omitempty" yaml:"prefixLen"`
+	// lifetimes in seconds, expiry in unix seconds
+	ValidLifetime     int64 `protobuf:"varint,5,opt,name=ValidLifetime,proto3" json:"ValidLifetime,omitempty" yaml:"validLifetime"`
+	PreferredLifetime int64 `protobuf:"varint,6,opt,name=PreferredLifetime,proto3" json:"PreferredLifetime,omitempty" yaml:"preferredLifetime"`
+	Expire            int64 `protobuf:"varint,7,opt,name=Expire,proto3" json:"Expire,omitempty" yaml:"expire"`
+	// "bound" or "declined"
+	State string `protobuf:"bytes,8,opt,name=State,proto3" json:"State,omitempty" yaml:"state"`
+	// hex encoded key authenticating the Reconfigure messages to the client
+	ReconfigureKey string `protobuf:"bytes,10,opt,name=ReconfigureKey,proto3" json:"ReconfigureKey,omitempty" yaml:"reconfigureKey"`
+}
+
+func (x *Lease) Reset() {
+	*x = Lease{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dhcp_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Lease) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Lease) ProtoMessage() {}
+
+func (x *Lease) ProtoReflect() protoreflect.Message {
+	mi := &file_dhcp_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Lease.ProtoReflect.Descriptor instead.
+func (*Lease) Descriptor() ([]byte, []int) {
+	return file_dhcp_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Lease) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *Lease) GetDUID() string {
+	if x != nil {
+		return x.DUID
+	}
+	return ""
+}
+
+func (x *Lease) GetIAID() string {
+	if x != nil {
+		return x.IAID
+	}
+	return ""
+}
+
+func (x *Lease) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+func (x *Lease) GetPrefixLen() int32 {
+	if x != nil {
+		return x.PrefixLen
+	}
+	return 0
+}
+
+func (x *Lease) GetValidLifetime() int64 {
+	if x != nil {
+		return x.ValidLifetime
+	}
+	return 0
+}
+
+func (x *Lease) GetPreferredLifetime() int64 {
+	if x != nil {
+		return x.PreferredLifetime
+	}
+	return 0
+}
+
+func (x *Lease) GetExpire() int64 {
+	if x != nil {
+		return x.Expire
+	}
+	return 0
+}
+
+func (x *Lease) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *Lease) GetReconfigureKey() string {
+	if x != nil {
+		return x.ReconfigureKey
+	}
+	return ""
+}
+
+var File_dhcp_proto protoreflect.FileDescriptor
+
+var file_dhcp_proto_rawDesc = []byte{
+	0x0a, 0x0a, 0x64, 0x68, 0x63, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x04, 0x64, 0x68,
+	0x63, 0x70, 0x22, 0xbe, 0x01, 0x0a, 0x06, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x16, 0x0a,
+	0x06, 0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x43,
+	0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x12, 0x36, 0x0a, 0x08, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b,
+	0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x64, 0x68, 0x63, 0x70, 0x2e, 0x43,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x73, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x52, 0x08, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x73, 0x12, 0x18, 0x0a,
+	0x07, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07,
+	0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x1a, 0x4a, 0x0a, 0x0d, 0x4e, 0x65, 0x74, 0x77, 0x6f,
+	0x72, 0x6b, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x23, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x64, 0x68, 0x63, 0x70,
+	0x2e, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a,
+	0x02, 0x38, 0x01, 0x22, 0xeb, 0x03, 0x0a, 0x07, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x12,
+	0x1a, 0x0a, 0x08, 0x4c, 0x69, 0x6e, 0x6b, 0x41, 0x64, 0x64, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x4c, 0x69, 0x6e, 0x6b, 0x41, 0x64, 0x64, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x47,
+	0x61, 0x74, 0x65, 0x77, 0x61, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x47, 0x61,
+	0x74, 0x65, 0x77, 0x61, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x50, 0x72, 0x65, 0x66, 0x69, 0x78, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x50, 0x72, 0x65, 0x66, 0x69, 0x78, 0x12, 0x1e, 0x0a,
+	0x0a, 0x53, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x4d, 0x61, 0x73, 0x6b, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0a, 0x53, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x4d, 0x61, 0x73, 0x6b, 0x12, 0x1a, 0x0a,
+	0x08, 0x52, 0x65, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x52, 0x65, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x54, 0x54, 0x4c,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x54, 0x54, 0x4c, 0x12, 0x20, 0x0a, 0x05, 0x50,
+	0x6f, 0x6f, 0x6c, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x64, 0x68, 0x63,
+	0x70, 0x2e, 0x50, 0x6f, 0x6f, 0x6c, 0x52, 0x05, 0x50, 0x6f, 0x6f, 0x6c, 0x73, 0x12, 0x24, 0x0a,
+	0x0d, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x4c, 0x69, 0x66, 0x65, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x08,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x4c, 0x69, 0x66, 0x65, 0x74,
+	0x69, 0x6d, 0x65, 0x12, 0x2c, 0x0a, 0x11, 0x50, 0x72, 0x65, 0x66, 0x65, 0x72, 0x72, 0x65, 0x64,
+	0x4c, 0x69, 0x66, 0x65, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x03, 0x52, 0x11,
+	0x50, 0x72, 0x65, 0x66, 0x65, 0x72, 0x72, 0x65, 0x64, 0x4c, 0x69, 0x66, 0x65, 0x74, 0x69, 0x6d,
+	0x65, 0x12, 0x28, 0x0a, 0x0f, 0x44, 0x65, 0x6c, 0x65, 0x67, 0x61, 0x74, 0x65, 0x64, 0x4c, 0x65,
+	0x6e, 0x67, 0x74, 0x68, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0f, 0x44, 0x65, 0x6c, 0x65,
+	0x67, 0x61, 0x74, 0x65, 0x64, 0x4c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x12, 0x35, 0x0a, 0x0c, 0x52,
+	0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x0b, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x11, 0x2e, 0x64, 0x68, 0x63, 0x70, 0x2e, 0x52, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0c, 0x52, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x12, 0x27, 0x0a, 0x07, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x0c, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x64, 0x68, 0x63, 0x70, 0x2e, 0x4f, 0x70, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x52, 0x07, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x22, 0x0a, 0x0c, 0x49,
+	0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x49, 0x44, 0x73, 0x18, 0x0d, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x0c, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x49, 0x44, 0x73, 0x12,
+	0x20, 0x0a, 0x0b, 0x52, 0x61, 0x70, 0x69, 0x64, 0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x18, 0x0e,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x0b, 0x52, 0x61, 0x70, 0x69, 0x64, 0x43, 0x6f, 0x6d, 0x6d, 0x69,
+	0x74, 0x22, 0x2e, 0x0a, 0x04, 0x50, 0x6f, 0x6f, 0x6c, 0x12, 0x14, 0x0a, 0x05, 0x42, 0x65, 0x67,
+	0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x42, 0x65, 0x67, 0x69, 0x6e, 0x12,
+	0x10, 0x0a, 0x03, 0x45, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x45, 0x6e,
+	0x64, 0x22, 0xda, 0x01, 0x0a, 0x0b, 0x52, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x12, 0x0a, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x44, 0x55, 0x49, 0x44, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x44, 0x55, 0x49, 0x44, 0x12, 0x10, 0x0a, 0x03, 0x4d, 0x41, 0x43,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x4d, 0x41, 0x43, 0x12, 0x20, 0x0a, 0x0b, 0x49,
+	0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x49, 0x44, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0b, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x49, 0x44, 0x12, 0x1a, 0x0a,
+	0x08, 0x52, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x49, 0x44, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x52, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x49, 0x44, 0x12, 0x12, 0x0a, 0x04, 0x41, 0x64, 0x64,
+	0x72, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x41, 0x64, 0x64, 0x72, 0x12, 0x16, 0x0a,
+	0x06, 0x50, 0x72, 0x65, 0x66, 0x69, 0x78, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x50,
+	0x72, 0x65, 0x66, 0x69, 0x78, 0x12, 0x27, 0x0a, 0x07, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x64, 0x68, 0x63, 0x70, 0x2e, 0x4f, 0x70,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x07, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x9e,
+	0x02, 0x0a, 0x07, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1e, 0x0a, 0x0a, 0x44, 0x4e,
+	0x53, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0a,
+	0x44, 0x4e, 0x53, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x73, 0x12, 0x1e, 0x0a, 0x0a, 0x44, 0x6f,
+	0x6d, 0x61, 0x69, 0x6e, 0x4c, 0x69, 0x73, 0x74, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0a,
+	0x44, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x20, 0x0a, 0x0b, 0x42, 0x6f,
+	0x6f, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x55, 0x52, 0x4c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0b, 0x42, 0x6f, 0x6f, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x55, 0x52, 0x4c, 0x12, 0x26, 0x0a, 0x0e,
+	0x42, 0x6f, 0x6f, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x18, 0x04,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x0e, 0x42, 0x6f, 0x6f, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x50, 0x61,
+	0x72, 0x61, 0x6d, 0x73, 0x12, 0x1e, 0x0a, 0x0a, 0x4e, 0x54, 0x50, 0x53, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0a, 0x4e, 0x54, 0x50, 0x53, 0x65, 0x72,
+	0x76, 0x65, 0x72, 0x73, 0x12, 0x38, 0x0a, 0x0d, 0x56, 0x65, 0x6e, 0x64, 0x6f, 0x72, 0x4f, 0x70,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x64, 0x68,
+	0x63, 0x70, 0x2e, 0x56, 0x65, 0x6e, 0x64, 0x6f, 0x72, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x52,
+	0x0d, 0x56, 0x65, 0x6e, 0x64, 0x6f, 0x72, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x2f,
+	0x0a, 0x0a, 0x52, 0x61, 0x77, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x07, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x64, 0x68, 0x63, 0x70, 0x2e, 0x52, 0x61, 0x77, 0x4f, 0x70, 0x74,
+	0x69, 0x6f, 0x6e, 0x52, 0x0a, 0x52, 0x61, 0x77, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22,
+	0x65, 0x0a, 0x0c, 0x56, 0x65, 0x6e, 0x64, 0x6f, 0x72, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x2a, 0x0a, 0x10, 0x45, 0x6e, 0x74, 0x65, 0x72, 0x70, 0x72, 0x69, 0x73, 0x65, 0x4e, 0x75, 0x6d,
+	0x62, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x10, 0x45, 0x6e, 0x74, 0x65, 0x72,
+	0x70, 0x72, 0x69, 0x73, 0x65, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x29, 0x0a, 0x07, 0x4f,
+	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x64,
+	0x68, 0x63, 0x70, 0x2e, 0x52, 0x61, 0x77, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x07, 0x4f,
+	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x33, 0x0a, 0x09, 0x52, 0x61, 0x77, 0x4f, 0x70, 0x74,
+	0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x43, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x04, 0x43, 0x6f, 0x64, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x44, 0x61, 0x74, 0x61, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x44, 0x61, 0x74, 0x61, 0x22, 0xa5, 0x02, 0x0a, 0x05,
+	0x4c, 0x65, 0x61, 0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x12,
+	0x12, 0x0a, 0x04, 0x44, 0x55, 0x49, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x44,
+	0x55, 0x49, 0x44, 0x12, 0x12, 0x0a, 0x04, 0x49, 0x41, 0x49, 0x44, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x49, 0x41, 0x49, 0x44, 0x12, 0x12, 0x0a, 0x04, 0x41, 0x64, 0x64, 0x72, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x41, 0x64, 0x64, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x50,
+	0x72, 0x65, 0x66, 0x69, 0x78, 0x4c, 0x65, 0x6e, 0x18, 0x09, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09,
+	0x50, 0x72, 0x65, 0x66, 0x69, 0x78, 0x4c, 0x65, 0x6e, 0x12, 0x24, 0x0a, 0x0d, 0x56, 0x61, 0x6c,
+	0x69, 0x64, 0x4c, 0x69, 0x66, 0x65, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0d, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x4c, 0x69, 0x66, 0x65, 0x74, 0x69, 0x6d, 0x65, 0x12,
+	0x2c, 0x0a, 0x11, 0x50, 0x72, 0x65, 0x66, 0x65, 0x72, 0x72, 0x65, 0x64, 0x4c, 0x69, 0x66, 0x65,
+	0x74, 0x69, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x11, 0x50, 0x72, 0x65, 0x66,
+	0x65, 0x72, 0x72, 0x65, 0x64, 0x4c, 0x69, 0x66, 0x65, 0x74, 0x69, 0x6d, 0x65, 0x12, 0x16, 0x0a,
+	0x06, 0x45, 0x78, 0x70, 0x69, 0x72, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x45,
+	0x78, 0x70, 0x69, 0x72, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x53, 0x74, 0x61, 0x74, 0x65, 0x18, 0x08,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x26, 0x0a, 0x0e, 0x52,
+	0x65, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x65, 0x4b, 0x65, 0x79, 0x18, 0x0a, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0e, 0x52, 0x65, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x65,
+	0x4b, 0x65, 0x79, 0x42, 0x07, 0x5a, 0x05, 0x2f, 0x64, 0x68, 0x63, 0x70, 0x62, 0x06, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_dhcp_proto_rawDescOnce sync.Once
+	file_dhcp_proto_rawDescData = file_dhcp_proto_rawDesc
+)
+
+func file_dhcp_proto_rawDescGZIP() []byte {
+	file_dhcp_proto_rawDescOnce.Do(func() {
+		file_dhcp_proto_rawDescData = protoimpl.X.CompressGZIP(file_dhcp_proto_rawDescData)
+	})
+	return file_dhcp_proto_rawDescData
+}
+
+var file_dhcp_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_dhcp_proto_goTypes = []interface{}{
+	(*Config)(nil),       // 0: dhcp.Config
+	(*Network)(nil),      // 1: dhcp.Network
+	(*Pool)(nil),         // 2: dhcp.Pool
+	(*Reservation)(nil),  // 3: dhcp.Reservation
+	(*Options)(nil),      // 4: dhcp.Options
+	(*VendorOption)(nil), // 5: dhcp.VendorOption
+	(*RawOption)(nil),    // 6: dhcp.RawOption
+	(*Lease)(nil),        // 7: dhcp.Lease
+	nil,                  // 8: dhcp.Config.NetworksEntry
+}
+var file_dhcp_proto_depIdxs = []int32{
+	8, // 0: dhcp.Config.Networks:type_name -> dhcp.Config.NetworksEntry
+	2, // 1: dhcp.Network.Pools:type_name -> dhcp.Pool
+	3, // 2: dhcp.Network.Reservations:type_name -> dhcp.Reservation
+	4, // 3: dhcp.Network.Options:type_name -> dhcp.Options
+	4, // 4: dhcp.Reservation.Options:type_name -> dhcp.Options
+	5, // 5: dhcp.Options.VendorOptions:type_name -> dhcp.VendorOption
+	6, // 6: dhcp.Options.RawOptions:type_name -> dhcp.RawOption
+	6, // 7: dhcp.VendorOption.Options:type_name -> dhcp.RawOption
+	1, // 8: dhcp.Config.NetworksEntry.value:type_name -> dhcp.Network
+	9, // [9:9] is the sub-list for method output_type
+	9, // [9:9] is the sub-list for method input_type
+	9, // [9:9] is the sub-list for extension type_name
+	9, // [9:9] is the sub-list for extension extendee
+	0, // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_dhcp_proto_init() }
+func file_dhcp_proto_init() {
+	if File_dhcp_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_dhcp_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Config); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dhcp_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Network); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dhcp_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Pool); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dhcp_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Reservation); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dhcp_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Options); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dhcp_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VendorOption); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dhcp_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RawOption); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dhcp_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Lease); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_dhcp_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_dhcp_proto_goTypes,
+		DependencyIndexes: file_dhcp_proto_depIdxs,
+		MessageInfos:      file_dhcp_proto_msgTypes,
+	}.Build()
+	File_dhcp_proto = out.File
+	file_dhcp_proto_rawDesc = nil
+	file_dhcp_proto_goTypes = nil
+	file_dhcp_proto_depIdxs = nil
+}
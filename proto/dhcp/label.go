@@ -0,0 +1,21 @@
+package dhcp
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MetricLabel turns a message type or status name into a metric label value,
+// NoAddrsAvail becomes no-addrs-avail and RELAY-REPL relay-repl
+func MetricLabel(name string) string {
+	out := []rune{}
+	prev := rune(0)
+	for _, r := range name {
+		if unicode.IsUpper(r) && unicode.IsLower(prev) {
+			out = append(out, '-')
+		}
+		out = append(out, unicode.ToLower(r))
+		prev = r
+	}
+	return strings.ReplaceAll(string(out), "_", "-")
+}
@@ -0,0 +1,19 @@
+package dhcp
+
+import "testing"
+
+func TestMetricLabel(t *testing.T) {
+	for name, want := range map[string]string{
+		"NoAddrsAvail":  "no-addrs-avail",
+		"RELAY-REPL":    "relay-repl",
+		"UseMulticast":  "use-multicast",
+		"NOT_ON_LINK":   "not-on-link",
+		"DHCPDISCOVER":  "dhcpdiscover",
+		"ÉtatNonValide": "état-non-valide",
+		"":              "",
+	} {
+		if got := MetricLabel(name); got != want {
+			t.Errorf("MetricLabel(%q) %q, want %q", name, got, want)
+		}
+	}
+}
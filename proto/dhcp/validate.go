@@ -0,0 +1,254 @@
+package dhcp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net"
+)
+
+// Version is the IaC apiVersion of the DHCP model this package implements
+const Version = "service/v1"
+
+// Validate checks the version and every network of the config, and that no
+// two networks share pool addresses or relay interface-ids
+func (c *Config) Validate() error {
+	if c.GetVersion() != "" && c.GetVersion() != Version {
+		return fmt.Errorf("unsupported dhcp model version %s", c.GetVersion())
+	}
+	type span struct {
+		name       string
+		begin, end net.IP
+	}
+	spans := []span{}
+	ifids := map[string]string{}
+	for name, n := range c.GetNetworks() {
+		if err := n.Validate(name); err != nil {
+			return err
+		}
+		// a dual-stack relay shares its interface-id between families
+		for _, id := range n.GetInterfaceIDs() {
+			key := fmt.Sprint(n.IPv4(), id)
+			if other, exist := ifids[key]; exist {
+				return fmt.Errorf("network %s interface-id %s already used by network %s", name, id, other)
+			}
+			ifids[key] = name
+		}
+		for _, p := range n.GetPools() {
+			s := span{name, net.ParseIP(p.GetBegin()).To16(), net.ParseIP(p.GetEnd()).To16()}
+			for _, o := range spans {
+				if bytes.Compare(s.begin, o.end) <= 0 && bytes.Compare(s.end, o.begin) >= 0 {
+					return fmt.Errorf("network %s pool %s - %s overlaps network %s pool %s - %s", s.name, s.begin, s.end, o.name, o.begin, o.end)
+				}
+			}
+			spans = append(spans, s)
+		}
+	}
+	return nil
+}
+
+// IPv4 reports whether the network is served by DHCPv4, as told by its prefix
+// or else its first pool
+func (n *Network) IPv4() bool {
+	if ip, _, err := net.ParseCIDR(n.GetPrefix()); err == nil {
+		return ip.To4() != nil
+	}
+	for _, p := range n.GetPools() {
+		return net.ParseIP(p.GetBegin()).To4() != nil
+	}
+	return false
+}
+
+// Validate checks the prefix, pools, lifetimes, reservations and options of
+// the network
+func (n *Network) Validate(name string) error {
+	var prefix *net.IPNet
+	if n.GetPrefix() != "" {
+		_, p, err := net.ParseCIDR(n.GetPrefix())
+		if err != nil {
+			return fmt.Errorf("network %s invalid prefix %s: %v", name, n.GetPrefix(), err)
+		}
+		prefix = p
+	}
+	if n.GetLinkAddr() != "" && net.ParseIP(n.GetLinkAddr()) == nil {
+		return fmt.Errorf("network %s invalid link address %s", name, n.GetLinkAddr())
+	}
+	for _, id := range n.GetInterfaceIDs() {
+		if b, err := hex.DecodeString(id); err != nil || len(b) == 0 {
+			return fmt.Errorf("network %s invalid interface-id %s", name, id)
+		}
+	}
+	for _, p := range n.GetPools() {
+		begin, end := net.ParseIP(p.GetBegin()), net.ParseIP(p.GetEnd())
+		if begin == nil || end == nil {
+			return fmt.Errorf("network %s invalid pool %s - %s", name, p.GetBegin(), p.GetEnd())
+		}
+		if bytes.Compare(begin.To16(), end.To16()) > 0 {
+			return fmt.Errorf("network %s pool begin %s is after end %s", name, p.GetBegin(), p.GetEnd())
+		}
+		if prefix != nil && (!prefix.Contains(begin) || !prefix.Contains(end)) {
+			return fmt.Errorf("network %s pool %s - %s is outside of prefix %s", name, p.GetBegin(), p.GetEnd(), prefix)
+		}
+		if (begin.To4() != nil) != n.IPv4() || (end.To4() != nil) != n.IPv4() {
+			return fmt.Errorf("network %s pool %s - %s mixes address families", name, p.GetBegin(), p.GetEnd())
+		}
+	}
+	if n.IPv4() {
+		if err := n.validate4(prefix); err != nil {
+			return fmt.Errorf("network %s %v", name, err)
+		}
+	}
+	if l := int(n.GetDelegatedLength()); l != 0 {
+		if prefix == nil {
+			return fmt.Errorf("network %s prefix delegation requires a prefix", name)
+		}
+		if ones, bits := prefix.Mask.Size(); bits != 128 || l <= ones || l > 128 {
+			return fmt.Errorf("network %s invalid delegated length %v for prefix %s", name, l, prefix)
+		}
+	}
+	if n.GetValidLifetime() < 0 || n.GetPreferredLifetime() < 0 {
+		return fmt.Errorf("network %s negative lifetime", name)
+	}
+	if n.GetValidLifetime() > 0 && n.GetPreferredLifetime() > n.GetValidLifetime() {
+		return fmt.Errorf("network %s preferred lifetime %v exceeds valid lifetime %v", name, n.GetPreferredLifetime(), n.GetValidLifetime())
+	}
+	if err := n.GetOptions().Validate(); err != nil {
+		return fmt.Errorf("network %s %v", name, err)
+	}
+	reserved := map[string]string{}
+	for i, r := range n.GetReservations() {
+		id := r.GetName()
+		if id == "" {
+			id = fmt.Sprint(i)
+		}
+		if err := r.validate(n, prefix); err != nil {
+			return fmt.Errorf("network %s reservation %s %v", name, id, err)
+		}
+		for _, a := range []string{r.GetAddr(), r.GetPrefix()} {
+			if a == "" {
+				continue
+			}
+			if other, exist := reserved[a]; exist {
+				return fmt.Errorf("network %s reservation %s %s already reserved by %s", name, id, a, other)
+			}
+			reserved[a] = id
+		}
+	}
+	return nil
+}
+
+// validate4 checks the DHCPv4 specific fields of an IPv4 network
+func (n *Network) validate4(prefix *net.IPNet) error {
+	if n.GetSubnetMask() != "" {
+		mask := net.ParseIP(n.GetSubnetMask()).To4()
+		ones, bits := net.IPMask(mask).Size()
+		if mask == nil || bits == 0 {
+			return fmt.Errorf("invalid subnet mask %s", n.GetSubnetMask())
+		}
+		if prefix != nil {
+			if plen, _ := prefix.Mask.Size(); ones != plen {
+				return fmt.Errorf("subnet mask %s does not match prefix %s", n.GetSubnetMask(), prefix)
+			}
+		}
+	} else if prefix == nil {
+		return fmt.Errorf("requires a prefix or a subnet mask")
+	}
+	if n.GetGateway() != "" {
+		gw := net.ParseIP(n.GetGateway()).To4()
+		if gw == nil {
+			return fmt.Errorf("invalid gateway %s", n.GetGateway())
+		}
+		if prefix != nil && !prefix.Contains(gw) {
+			return fmt.Errorf("gateway %s is outside of prefix %s", gw, prefix)
+		}
+	}
+	if n.GetDelegatedLength() != 0 {
+		return fmt.Errorf("prefix delegation requires an IPv6 prefix")
+	}
+	if n.GetRapidCommit() {
+		return fmt.Errorf("rapid commit is supported for DHCPv6 only")
+	}
+	opts := []*Options{n.GetOptions()}
+	for _, r := range n.GetReservations() {
+		if r.GetPrefix() != "" {
+			return fmt.Errorf("reservation %s prefix requires an IPv6 network", r.GetName())
+		}
+		if r.GetAddr() != "" && net.ParseIP(r.GetAddr()).To4() == nil {
+			return fmt.Errorf("reservation %s address %s is not IPv4", r.GetName(), r.GetAddr())
+		}
+		opts = append(opts, r.GetOptions())
+	}
+	for _, o := range opts {
+		for _, r := range o.GetRawOptions() {
+			if r.GetCode() > 0xff {
+				return fmt.Errorf("invalid DHCPv4 option code %v", r.GetCode())
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Reservation) validate(n *Network, prefix *net.IPNet) error {
+	if r.GetDUID() == "" && r.GetMAC() == "" && r.GetInterfaceID() == "" && r.GetRemoteID() == "" {
+		return fmt.Errorf("without host identifier")
+	}
+	for _, h := range []string{r.GetDUID(), r.GetInterfaceID(), r.GetRemoteID()} {
+		if _, err := hex.DecodeString(h); err != nil {
+			return fmt.Errorf("invalid identifier %s: %v", h, err)
+		}
+	}
+	if r.GetMAC() != "" {
+		if _, err := net.ParseMAC(r.GetMAC()); err != nil {
+			return fmt.Errorf("invalid MAC %s: %v", r.GetMAC(), err)
+		}
+	}
+	if r.GetAddr() == "" && r.GetPrefix() == "" {
+		return fmt.Errorf("without address or prefix")
+	}
+	if r.GetAddr() != "" {
+		addr := net.ParseIP(r.GetAddr())
+		if addr == nil {
+			return fmt.Errorf("invalid address %s", r.GetAddr())
+		}
+		if prefix != nil && !prefix.Contains(addr) {
+			return fmt.Errorf("address %s is outside of prefix %s", addr, prefix)
+		}
+		// pool addresses are handed out dynamically
+		for _, p := range n.GetPools() {
+			if bytes.Compare(addr.To16(), net.ParseIP(p.GetBegin()).To16()) >= 0 && bytes.Compare(addr.To16(), net.ParseIP(p.GetEnd()).To16()) <= 0 {
+				return fmt.Errorf("address %s is inside pool %s - %s", addr, p.GetBegin(), p.GetEnd())
+			}
+		}
+	}
+	if r.GetPrefix() != "" {
+		ip, p, err := net.ParseCIDR(r.GetPrefix())
+		if err != nil || !ip.Equal(p.IP) {
+			return fmt.Errorf("invalid prefix %s", r.GetPrefix())
+		}
+	}
+	return r.GetOptions().Validate()
+}
+
+// Validate checks the addresses and encoded data of the options
+func (o *Options) Validate() error {
+	for _, list := range [][]string{o.GetDNSServers(), o.GetNTPServers()} {
+		for _, a := range list {
+			if net.ParseIP(a) == nil {
+				return fmt.Errorf("invalid server address %s", a)
+			}
+		}
+	}
+	raw := append([]*RawOption{}, o.GetRawOptions()...)
+	for _, v := range o.GetVendorOptions() {
+		raw = append(raw, v.GetOptions()...)
+	}
+	for _, r := range raw {
+		if r.GetCode() == 0 || r.GetCode() > 0xffff {
+			return fmt.Errorf("invalid option code %v", r.GetCode())
+		}
+		if _, err := hex.DecodeString(r.GetData()); err != nil {
+			return fmt.Errorf("invalid option %v data %s: %v", r.GetCode(), r.GetData(), err)
+		}
+	}
+	return nil
+}
@@ -27,6 +27,12 @@ type Zone struct {
 
 	Commit  string               `protobuf:"bytes,1,opt,name=Commit,proto3" json:"Commit,omitempty"`
 	Records map[string]*Category `protobuf:"bytes,2,rep,name=Records,proto3" json:"Records,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3" yaml:"records"`
+	// apexes of the zones served authoritatively, each with its SOA record,
+	// every record name falls under one of them
+	Origins []string `protobuf:"bytes,3,rep,name=Origins,proto3" json:"Origins,omitempty" yaml:"origins"`
+	// upstreams recursive queries for names out of the origins are forwarded
+	// to, by the closest zone
+	Forwarders []*Forwarder `protobuf:"bytes,4,rep,name=Forwarders,proto3" json:"Forwarders,omitempty" yaml:"forwarders"`
 }
 
 func (x *Zone) Reset() {
@@ -75,6 +81,77 @@ func (x *Zone) GetRecords() map[string]*Category {
 	return nil
 }
 
+func (x *Zone) GetOrigins() []string {
+	if x != nil {
+		return x.Origins
+	}
+	return nil
+}
+
+func (x *Zone) GetForwarders() []*Forwarder {
+	if x != nil {
+		return x.Forwarders
+	}
+	return nil
+}
+
+type Forwarder struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// zone the upstreams resolve, "." for every name
+	Zone string `protobuf:"bytes,1,opt,name=Zone,proto3" json:"Zone,omitempty" yaml:"zone"`
+	// address and optional port, 53 by default
+	Upstreams []string `protobuf:"bytes,2,rep,name=Upstreams,proto3" json:"Upstreams,omitempty" yaml:"upstreams"`
+}
+
+func (x *Forwarder) Reset() {
+	*x = Forwarder{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dns_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Forwarder) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Forwarder) ProtoMessage() {}
+
+func (x *Forwarder) ProtoReflect() protoreflect.Message {
+	mi := &file_dns_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Forwarder.ProtoReflect.Descriptor instead.
+func (*Forwarder) Descriptor() ([]byte, []int) {
+	return file_dns_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Forwarder) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+func (x *Forwarder) GetUpstreams() []string {
+	if x != nil {
+		return x.Upstreams
+	}
+	return nil
+}
+
 type Category struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -86,7 +163,7 @@ type Category struct {
 func (x *Category) Reset() {
 	*x = Category{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_dns_proto_msgTypes[1]
+		mi := &file_dns_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -99,7 +176,7 @@ func (x *Category) String() string {
 func (*Category) ProtoMessage() {}
 
 func (x *Category) ProtoReflect() protoreflect.Message {
-	mi := &file_dns_proto_msgTypes[1]
+	mi := &file_dns_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -112,7 +189,7 @@ func (x *Category) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Category.ProtoReflect.Descriptor instead.
 func (*Category) Descriptor() ([]byte, []int) {
-	return file_dns_proto_rawDescGZIP(), []int{1}
+	return file_dns_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *Category) GetType() map[string]*Record {
@@ -122,19 +199,32 @@ func (x *Category) GetType() map[string]*Record {
 	return nil
 }
 
+// Record is the RRset of one type of a name, RDATA is read from the field of
+// the type
 type Record struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
+	// A and AAAA
 	Addr []string `protobuf:"bytes,1,rep,name=Addr,proto3" json:"Addr,omitempty" yaml:"addr"`
 	TTL  int64    `protobuf:"varint,2,opt,name=TTL,proto3" json:"TTL,omitempty" yaml:"ttl"`
+	// CNAME
+	Target string `protobuf:"bytes,3,opt,name=Target,proto3" json:"Target,omitempty" yaml:"target"`
+	// NS and PTR
+	Hosts []string `protobuf:"bytes,4,rep,name=Hosts,proto3" json:"Hosts,omitempty" yaml:"hosts"`
+	MX    []*MX    `protobuf:"bytes,5,rep,name=MX,proto3" json:"MX,omitempty" yaml:"mx"`
+	// TXT, one record per string
+	Text []string `protobuf:"bytes,6,rep,name=Text,proto3" json:"Text,omitempty" yaml:"text"`
+	SRV  []*SRV   `protobuf:"bytes,7,rep,name=SRV,proto3" json:"SRV,omitempty" yaml:"srv"`
+	SOA  *SOA     `protobuf:"bytes,8,opt,name=SOA,proto3" json:"SOA,omitempty" yaml:"soa"`
+	CAA  []*CAA   `protobuf:"bytes,9,rep,name=CAA,proto3" json:"CAA,omitempty" yaml:"caa"`
 }
 
 func (x *Record) Reset() {
 	*x = Record{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_dns_proto_msgTypes[2]
+		mi := &file_dns_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -147,7 +237,7 @@ func (x *Record) String() string {
 func (*Record) ProtoMessage() {}
 
 func (x *Record) ProtoReflect() protoreflect.Message {
-	mi := &file_dns_proto_msgTypes[2]
+	mi := &file_dns_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -160,7 +250,7 @@ func (x *Record) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Record.ProtoReflect.Descriptor instead.
 func (*Record) Descriptor() ([]byte, []int) {
-	return file_dns_proto_rawDescGZIP(), []int{2}
+	return file_dns_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *Record) GetAddr() []string {
@@ -177,32 +267,410 @@ func (x *Record) GetTTL() int64 {
 	return 0
 }
 
+func (x *Record) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *Record) GetHosts() []string {
+	if x != nil {
+		return x.Hosts
+	}
+	return nil
+}
+
+func (x *Record) GetMX() []*MX {
+	if x != nil {
+		return x.MX
+	}
+	return nil
+}
+
+func (x *Record) GetText() []string {
+	if x != nil {
+		return x.Text
+	}
+	return nil
+}
+
+func (x *Record) GetSRV() []*SRV {
+	if x != nil {
+		return x.SRV
+	}
+	return nil
+}
+
+func (x *Record) GetSOA() *SOA {
+	if x != nil {
+		return x.SOA
+	}
+	return nil
+}
+
+func (x *Record) GetCAA() []*CAA {
+	if x != nil {
+		return x.CAA
+	}
+	return nil
+}
+
+type MX struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Preference uint32 `protobuf:"varint,1,opt,name=Preference,proto3" json:"Preference,omitempty" yaml:"preference"`
+	Exchange   string `protobuf:"bytes,2,opt,name=Exchange,proto3" json:"Exchange,omitempty" yaml:"exchange"`
+}
+
+func (x *MX) Reset() {
+	*x = MX{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dns_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MX) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MX) ProtoMessage() {}
+
+func (x *MX) ProtoReflect() protoreflect.Message {
+	mi := &file_dns_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MX.ProtoReflect.Descriptor instead.
+func (*MX) Descriptor() ([]byte, []int) {
+	return file_dns_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *MX) GetPreference() uint32 {
+	if x != nil {
+		return x.Preference
+	}
+	return 0
+}
+
+func (x *MX) GetExchange() string {
+	if x != nil {
+		return x.Exchange
+	}
+	return ""
+}
+
+type SRV struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Priority uint32 `protobuf:"varint,1,opt,name=Priority,proto3" json:"Priority,omitempty" yaml:"priority"`
+	Weight   uint32 `protobuf:"varint,2,opt,name=Weight,proto3" json:"Weight,omitempty" yaml:"weight"`
+	Port     uint32 `protobuf:"varint,3,opt,name=Port,proto3" json:"Port,omitempty" yaml:"port"`
+	Target   string `protobuf:"bytes,4,opt,name=Target,proto3" json:"Target,omitempty" yaml:"target"`
+}
+
+func (x *SRV) Reset() {
+	*x = SRV{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dns_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SRV) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SRV) ProtoMessage() {}
+
+func (x *SRV) ProtoReflect() protoreflect.Message {
+	mi := &file_dns_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SRV.ProtoReflect.Descriptor instead.
+func (*SRV) Descriptor() ([]byte, []int) {
+	return file_dns_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SRV) GetPriority() uint32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *SRV) GetWeight() uint32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (x *SRV) GetPort() uint32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *SRV) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+type SOA struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MName   string `protobuf:"bytes,1,opt,name=MName,proto3" json:"MName,omitempty" yaml:"mName"`
+	RName   string `protobuf:"bytes,2,opt,name=RName,proto3" json:"RName,omitempty" yaml:"rName"`
+	Serial  uint32 `protobuf:"varint,3,opt,name=Serial,proto3" json:"Serial,omitempty" yaml:"serial"`
+	Refresh uint32 `protobuf:"varint,4,opt,name=Refresh,proto3" json:"Refresh,omitempty" yaml:"refresh"`
+	Retry   uint32 `protobuf:"varint,5,opt,name=Retry,proto3" json:"Retry,omitempty" yaml:"retry"`
+	Expire  uint32 `protobuf:"varint,6,opt,name=Expire,proto3" json:"Expire,omitempty" yaml:"expire"`
+	Minimum uint32 `protobuf:"varint,7,opt,name=Minimum,proto3" json:"Minimum,omitempty" yaml:"minimum"`
+}
+
+func (x *SOA) Reset() {
+	*x = SOA{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dns_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SOA) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SOA) ProtoMessage() {}
+
+func (x *SOA) ProtoReflect() protoreflect.Message {
+	mi := &file_dns_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SOA.ProtoReflect.Descriptor instead.
+func (*SOA) Descriptor() ([]byte, []int) {
+	return file_dns_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SOA) GetMName() string {
+	if x != nil {
+		return x.MName
+	}
+	return ""
+}
+
+func (x *SOA) GetRName() string {
+	if x != nil {
+		return x.RName
+	}
+	return ""
+}
+
+func (x *SOA) GetSerial() uint32 {
+	if x != nil {
+		return x.Serial
+	}
+	return 0
+}
+
+func (x *SOA) GetRefresh() uint32 {
+	if x != nil {
+		return x.Refresh
+	}
+	return 0
+}
+
+func (x *SOA) GetRetry() uint32 {
+	if x != nil {
+		return x.Retry
+	}
+	return 0
+}
+
+func (x *SOA) GetExpire() uint32 {
+	if x != nil {
+		return x.Expire
+	}
+	return 0
+}
+
+func (x *SOA) GetMinimum() uint32 {
+	if x != nil {
+		return x.Minimum
+	}
+	return 0
+}
+
+type CAA struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Flag  uint32 `protobuf:"varint,1,opt,name=Flag,proto3" json:"Flag,omitempty" yaml:"flag"`
+	Tag   string `protobuf:"bytes,2,opt,name=Tag,proto3" json:"Tag,omitempty" yaml:"tag"`
+	Value string `protobuf:"bytes,3,opt,name=Value,proto3" json:"Value,omitempty" yaml:"value"`
+}
+
+func (x *CAA) Reset() {
+	*x = CAA{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dns_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CAA) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CAA) ProtoMessage() {}
+
+func (x *CAA) ProtoReflect() protoreflect.Message {
+	mi := &file_dns_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CAA.ProtoReflect.Descriptor instead.
+func (*CAA) Descriptor() ([]byte, []int) {
+	return file_dns_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CAA) GetFlag() uint32 {
+	if x != nil {
+		return x.Flag
+	}
+	return 0
+}
+
+func (x *CAA) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *CAA) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
 var File_dns_proto protoreflect.FileDescriptor
 
 var file_dns_proto_rawDesc = []byte{
 	0x0a, 0x09, 0x64, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x03, 0x64, 0x6e, 0x73,
-	0x22, 0x9b, 0x01, 0x0a, 0x04, 0x5a, 0x6f, 0x6e, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x43, 0x6f, 0x6d,
+	0x22, 0xe5, 0x01, 0x0a, 0x04, 0x5a, 0x6f, 0x6e, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x43, 0x6f, 0x6d,
 	0x6d, 0x69, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x43, 0x6f, 0x6d, 0x6d, 0x69,
 	0x74, 0x12, 0x30, 0x0a, 0x07, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x73, 0x18, 0x02, 0x20, 0x03,
 	0x28, 0x0b, 0x32, 0x16, 0x2e, 0x64, 0x6e, 0x73, 0x2e, 0x5a, 0x6f, 0x6e, 0x65, 0x2e, 0x52, 0x65,
 	0x63, 0x6f, 0x72, 0x64, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x52, 0x65, 0x63, 0x6f,
-	0x72, 0x64, 0x73, 0x1a, 0x49, 0x0a, 0x0c, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x73, 0x45, 0x6e,
-	0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x23, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x64, 0x6e, 0x73, 0x2e, 0x43, 0x61, 0x74, 0x65, 0x67,
-	0x6f, 0x72, 0x79, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x7d,
-	0x0a, 0x08, 0x43, 0x61, 0x74, 0x65, 0x67, 0x6f, 0x72, 0x79, 0x12, 0x2b, 0x0a, 0x04, 0x54, 0x79,
-	0x70, 0x65, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x64, 0x6e, 0x73, 0x2e, 0x43,
-	0x61, 0x74, 0x65, 0x67, 0x6f, 0x72, 0x79, 0x2e, 0x54, 0x79, 0x70, 0x65, 0x45, 0x6e, 0x74, 0x72,
-	0x79, 0x52, 0x04, 0x54, 0x79, 0x70, 0x65, 0x1a, 0x44, 0x0a, 0x09, 0x54, 0x79, 0x70, 0x65, 0x45,
-	0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x21, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x64, 0x6e, 0x73, 0x2e, 0x52, 0x65, 0x63, 0x6f,
-	0x72, 0x64, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x2e, 0x0a,
-	0x06, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x41, 0x64, 0x64, 0x72, 0x18,
-	0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x41, 0x64, 0x64, 0x72, 0x12, 0x10, 0x0a, 0x03, 0x54,
-	0x54, 0x4c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x54, 0x54, 0x4c, 0x42, 0x06, 0x5a,
-	0x04, 0x2f, 0x64, 0x6e, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x72, 0x64, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x4f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x73, 0x18, 0x03,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x4f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x73, 0x12, 0x2e, 0x0a,
+	0x0a, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x65, 0x72, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x0e, 0x2e, 0x64, 0x6e, 0x73, 0x2e, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x65,
+	0x72, 0x52, 0x0a, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x65, 0x72, 0x73, 0x1a, 0x49, 0x0a,
+	0x0c, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a,
+	0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12,
+	0x23, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d,
+	0x2e, 0x64, 0x6e, 0x73, 0x2e, 0x43, 0x61, 0x74, 0x65, 0x67, 0x6f, 0x72, 0x79, 0x52, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x3d, 0x0a, 0x09, 0x46, 0x6f, 0x72, 0x77,
+	0x61, 0x72, 0x64, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x5a, 0x6f, 0x6e, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x5a, 0x6f, 0x6e, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x55, 0x70, 0x73,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x09, 0x55, 0x70,
+	0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x22, 0x7d, 0x0a, 0x08, 0x43, 0x61, 0x74, 0x65, 0x67,
+	0x6f, 0x72, 0x79, 0x12, 0x2b, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x17, 0x2e, 0x64, 0x6e, 0x73, 0x2e, 0x43, 0x61, 0x74, 0x65, 0x67, 0x6f, 0x72, 0x79,
+	0x2e, 0x54, 0x79, 0x70, 0x65, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x04, 0x54, 0x79, 0x70, 0x65,
+	0x1a, 0x44, 0x0a, 0x09, 0x54, 0x79, 0x70, 0x65, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a,
+	0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12,
+	0x21, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b,
+	0x2e, 0x64, 0x6e, 0x73, 0x2e, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x52, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0xdd, 0x01, 0x0a, 0x06, 0x52, 0x65, 0x63, 0x6f, 0x72,
+	0x64, 0x12, 0x12, 0x0a, 0x04, 0x41, 0x64, 0x64, 0x72, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x04, 0x41, 0x64, 0x64, 0x72, 0x12, 0x10, 0x0a, 0x03, 0x54, 0x54, 0x4c, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x03, 0x54, 0x54, 0x4c, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x61, 0x72, 0x67, 0x65,
+	0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12,
+	0x14, 0x0a, 0x05, 0x48, 0x6f, 0x73, 0x74, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05,
+	0x48, 0x6f, 0x73, 0x74, 0x73, 0x12, 0x17, 0x0a, 0x02, 0x4d, 0x58, 0x18, 0x05, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x07, 0x2e, 0x64, 0x6e, 0x73, 0x2e, 0x4d, 0x58, 0x52, 0x02, 0x4d, 0x58, 0x12, 0x12,
+	0x0a, 0x04, 0x54, 0x65, 0x78, 0x74, 0x18, 0x06, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x54, 0x65,
+	0x78, 0x74, 0x12, 0x1a, 0x0a, 0x03, 0x53, 0x52, 0x56, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x08, 0x2e, 0x64, 0x6e, 0x73, 0x2e, 0x53, 0x52, 0x56, 0x52, 0x03, 0x53, 0x52, 0x56, 0x12, 0x1a,
+	0x0a, 0x03, 0x53, 0x4f, 0x41, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x64, 0x6e,
+	0x73, 0x2e, 0x53, 0x4f, 0x41, 0x52, 0x03, 0x53, 0x4f, 0x41, 0x12, 0x1a, 0x0a, 0x03, 0x43, 0x41,
+	0x41, 0x18, 0x09, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x64, 0x6e, 0x73, 0x2e, 0x43, 0x41,
+	0x41, 0x52, 0x03, 0x43, 0x41, 0x41, 0x22, 0x40, 0x0a, 0x02, 0x4d, 0x58, 0x12, 0x1e, 0x0a, 0x0a,
+	0x50, 0x72, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x0a, 0x50, 0x72, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x1a, 0x0a, 0x08,
+	0x45, 0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08,
+	0x45, 0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x22, 0x65, 0x0a, 0x03, 0x53, 0x52, 0x56, 0x12,
+	0x1a, 0x0a, 0x08, 0x50, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x08, 0x50, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x57,
+	0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x57, 0x65, 0x69,
+	0x67, 0x68, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x50, 0x6f, 0x72, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x04, 0x50, 0x6f, 0x72, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x61, 0x72, 0x67, 0x65,
+	0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x22,
+	0xab, 0x01, 0x0a, 0x03, 0x53, 0x4f, 0x41, 0x12, 0x14, 0x0a, 0x05, 0x4d, 0x4e, 0x61, 0x6d, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x4d, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a,
+	0x05, 0x52, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x52, 0x4e,
+	0x61, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x53, 0x65, 0x72, 0x69, 0x61, 0x6c, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x06, 0x53, 0x65, 0x72, 0x69, 0x61, 0x6c, 0x12, 0x18, 0x0a, 0x07, 0x52,
+	0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x52, 0x65,
+	0x66, 0x72, 0x65, 0x73, 0x68, 0x12, 0x14, 0x0a, 0x05, 0x52, 0x65, 0x74, 0x72, 0x79, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x52, 0x65, 0x74, 0x72, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x45,
+	0x78, 0x70, 0x69, 0x72, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x45, 0x78, 0x70,
+	0x69, 0x72, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x4d, 0x69, 0x6e, 0x69, 0x6d, 0x75, 0x6d, 0x18, 0x07,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x4d, 0x69, 0x6e, 0x69, 0x6d, 0x75, 0x6d, 0x22, 0x41, 0x0a,
+	0x03, 0x43, 0x41, 0x41, 0x12, 0x12, 0x0a, 0x04, 0x46, 0x6c, 0x61, 0x67, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x04, 0x46, 0x6c, 0x61, 0x67, 0x12, 0x10, 0x0a, 0x03, 0x54, 0x61, 0x67, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x54, 0x61, 0x67, 0x12, 0x14, 0x0a, 0x05, 0x56, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x56, 0x61, 0x6c, 0x75, 0x65,
+	0x42, 0x06, 0x5a, 0x04, 0x2f, 0x64, 0x6e, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -217,24 +685,34 @@ func file_dns_proto_rawDescGZIP() []byte {
 	return file_dns_proto_rawDescData
 }
 
-var file_dns_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_dns_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_dns_proto_goTypes = []interface{}{
-	(*Zone)(nil),     // 0: dns.Zone
-	(*Category)(nil), // 1: dns.Category
-	(*Record)(nil),   // 2: dns.Record
-	nil,              // 3: dns.Zone.RecordsEntry
-	nil,              // 4: dns.Category.TypeEntry
+	(*Zone)(nil),      // 0: dns.Zone
+	(*Forwarder)(nil), // 1: dns.Forwarder
+	(*Category)(nil),  // 2: dns.Category
+	(*Record)(nil),    // 3: dns.Record
+	(*MX)(nil),        // 4: dns.MX
+	(*SRV)(nil),       // 5: dns.SRV
+	(*SOA)(nil),       // 6: dns.SOA
+	(*CAA)(nil),       // 7: dns.CAA
+	nil,               // 8: dns.Zone.RecordsEntry
+	nil,               // 9: dns.Category.TypeEntry
 }
 var file_dns_proto_depIdxs = []int32{
-	3, // 0: dns.Zone.Records:type_name -> dns.Zone.RecordsEntry
-	4, // 1: dns.Category.Type:type_name -> dns.Category.TypeEntry
-	1, // 2: dns.Zone.RecordsEntry.value:type_name -> dns.Category
-	2, // 3: dns.Category.TypeEntry.value:type_name -> dns.Record
-	4, // [4:4] is the sub-list for method output_type
-	4, // [4:4] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	8, // 0: dns.Zone.Records:type_name -> dns.Zone.RecordsEntry
+	1, // 1: dns.Zone.Forwarders:type_name -> dns.Forwarder
+	9, // 2: dns.Category.Type:type_name -> dns.Category.TypeEntry
+	4, // 3: dns.Record.MX:type_name -> dns.MX
+	5, // 4: dns.Record.SRV:type_name -> dns.SRV
+	6, // 5: dns.Record.SOA:type_name -> dns.SOA
+	7, // 6: dns.Record.CAA:type_name -> dns.CAA
+	2, // 7: dns.Zone.RecordsEntry.value:type_name -> dns.Category
+	3, // 8: dns.Category.TypeEntry.value:type_name -> dns.Record
+	9, // [9:9] is the sub-list for method output_type
+	9, // [9:9] is the sub-list for method input_type
+	9, // [9:9] is the sub-list for extension type_name
+	9, // [9:9] is the sub-list for extension extendee
+	0, // [0:9] is the sub-list for field type_name
 }
 
 func init() { file_dns_proto_init() }
@@ -256,7 +734,7 @@ func file_dns_proto_init() {
 			}
 		}
 		file_dns_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Category); i {
+			switch v := v.(*Forwarder); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -268,6 +746,18 @@ func file_dns_proto_init() {
 			}
 		}
 		file_dns_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Category); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dns_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Record); i {
 			case 0:
 				return &v.state
@@ -279,6 +769,54 @@ func file_dns_proto_init() {
 				return nil
 			}
 		}
+		file_dns_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MX); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dns_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SRV); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dns_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SOA); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dns_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CAA); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -286,7 +824,7 @@ func file_dns_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_dns_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   5,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,168 @@
+package dns
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// TYPES are the record types a zone may carry
+var TYPES = []string{"A", "AAAA", "CNAME", "MX", "TXT", "SRV", "PTR", "NS", "SOA", "CAA"}
+
+// Validate checks every record of the zone, that a name holding a CNAME
+// holds nothing else, that every origin has its SOA record and every record
+// falls under an origin, and the forwarders
+func (z *Zone) Validate() error {
+	origins := map[string]bool{}
+	for _, o := range z.GetOrigins() {
+		if Canonical(o) == "." || origins[Canonical(o)] {
+			return fmt.Errorf("invalid or duplicated origin %q", o)
+		}
+		origins[Canonical(o)] = true
+	}
+	soa := map[string]bool{}
+	for name, c := range z.GetRecords() {
+		if Canonical(name) == "." {
+			return fmt.Errorf("invalid record name %q", name)
+		}
+		// RFC 4592 wildcards are the leftmost label only
+		if strings.Contains(strings.TrimPrefix(Canonical(name), "*."), "*") {
+			return fmt.Errorf("invalid wildcard name %s", name)
+		}
+		if Origin(z.GetOrigins(), name) == "" {
+			return fmt.Errorf("%s outside of the origins", name)
+		}
+		if _, exist := c.GetType()["SOA"]; exist {
+			if !origins[Canonical(name)] {
+				return fmt.Errorf("%s SOA not at an origin", name)
+			}
+			soa[Canonical(name)] = true
+		}
+		if _, exist := c.GetType()["CNAME"]; exist && len(c.GetType()) > 1 {
+			return fmt.Errorf("%s CNAME along with other records", name)
+		}
+		for t, r := range c.GetType() {
+			if err := r.Validate(t); err != nil {
+				return fmt.Errorf("%s %s %v", name, t, err)
+			}
+		}
+	}
+	for _, o := range z.GetOrigins() {
+		if !soa[Canonical(o)] {
+			return fmt.Errorf("origin %s without SOA record", o)
+		}
+	}
+	zones := map[string]bool{}
+	for _, f := range z.GetForwarders() {
+		if zones[Canonical(f.GetZone())] {
+			return fmt.Errorf("duplicated forwarder zone %q", f.GetZone())
+		}
+		zones[Canonical(f.GetZone())] = true
+		if len(f.GetUpstreams()) == 0 {
+			return fmt.Errorf("forwarder zone %q without upstream", f.GetZone())
+		}
+		for _, u := range f.GetUpstreams() {
+			if _, err := UpstreamAddr(u); err != nil {
+				return fmt.Errorf("forwarder zone %q %v", f.GetZone(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// UpstreamAddr returns the host:port form of the address and optional port
+// of a forwarder upstream
+func UpstreamAddr(s string) (string, error) {
+	if net.ParseIP(s) != nil {
+		return net.JoinHostPort(s, "53"), nil
+	}
+	host, port, err := net.SplitHostPort(s)
+	if err != nil || net.ParseIP(host) == nil {
+		return "", fmt.Errorf("invalid upstream %s", s)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 0xffff {
+		return "", fmt.Errorf("invalid upstream port %s", s)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// Origin returns the closest of the origins the name falls under, empty if
+// none
+func Origin(origins []string, name string) string {
+	n, closest := Canonical(name), ""
+	for _, o := range origins {
+		c := Canonical(o)
+		if (n == c || strings.HasSuffix(n, "."+c)) && len(c) > len(Canonical(closest)) {
+			closest = o
+		}
+	}
+	return closest
+}
+
+// Canonical is the lower case fully qualified form of the name, zone names
+// and query names are compared in it. The root is "."
+func Canonical(name string) string {
+	return strings.ToLower(strings.TrimRight(name, ".")) + "."
+}
+
+// Validate checks the record carries the RDATA of the type
+func (r *Record) Validate(t string) error {
+	if r.GetTTL() < 0 || r.GetTTL() > 0x7fffffff {
+		return fmt.Errorf("invalid ttl %v", r.GetTTL())
+	}
+	empty := func(n int) error {
+		if n == 0 {
+			return fmt.Errorf("without data")
+		}
+		return nil
+	}
+	switch t {
+	case "A", "AAAA":
+		for _, a := range r.GetAddr() {
+			ip := net.ParseIP(a)
+			if ip == nil || (ip.To4() != nil) != (t == "A") {
+				return fmt.Errorf("invalid address %s", a)
+			}
+		}
+		return empty(len(r.GetAddr()))
+	case "CNAME":
+		return empty(len(r.GetTarget()))
+	case "NS", "PTR":
+		for _, h := range r.GetHosts() {
+			if h == "" {
+				return fmt.Errorf("empty host")
+			}
+		}
+		return empty(len(r.GetHosts()))
+	case "MX":
+		for _, mx := range r.GetMX() {
+			if mx.GetPreference() > 0xffff || mx.GetExchange() == "" {
+				return fmt.Errorf("invalid mx %v %s", mx.GetPreference(), mx.GetExchange())
+			}
+		}
+		return empty(len(r.GetMX()))
+	case "TXT":
+		return empty(len(r.GetText()))
+	case "SRV":
+		for _, s := range r.GetSRV() {
+			if s.GetPriority() > 0xffff || s.GetWeight() > 0xffff || s.GetPort() > 0xffff || s.GetTarget() == "" {
+				return fmt.Errorf("invalid srv %v %v %v %s", s.GetPriority(), s.GetWeight(), s.GetPort(), s.GetTarget())
+			}
+		}
+		return empty(len(r.GetSRV()))
+	case "SOA":
+		if r.GetSOA().GetMName() == "" || r.GetSOA().GetRName() == "" {
+			return fmt.Errorf("requires mName and rName")
+		}
+		return nil
+	case "CAA":
+		for _, c := range r.GetCAA() {
+			if c.GetFlag() > 0xff || c.GetTag() == "" {
+				return fmt.Errorf("invalid caa %v %s", c.GetFlag(), c.GetTag())
+			}
+		}
+		return empty(len(r.GetCAA()))
+	}
+	return fmt.Errorf("unsupported type, one of %s", strings.Join(TYPES, " "))
+}
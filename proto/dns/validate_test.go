@@ -0,0 +1,34 @@
+package dns
+
+import "testing"
+
+func TestCanonical(t *testing.T) {
+	for name, want := range map[string]string{
+		"www.Example.com":  "www.example.com.",
+		"www.example.com.": "www.example.com.",
+		"example.com..":    "example.com.",
+		".":                ".",
+		"":                 ".",
+	} {
+		if got := Canonical(name); got != want {
+			t.Errorf("Canonical(%q) %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestOrigin(t *testing.T) {
+	origins := []string{"example.com", "Sub.Example.com."}
+	for name, want := range map[string]string{
+		"example.com.":       "example.com",
+		"www.EXAMPLE.com":    "example.com",
+		"a.sub.example.com.": "Sub.Example.com.",
+		"sub.example.com":    "Sub.Example.com.",
+		"notexample.com.":    "",
+		"example.com.org.":   "",
+		".":                  "",
+	} {
+		if got := Origin(origins, name); got != want {
+			t.Errorf("Origin(%q) %q, want %q", name, got, want)
+		}
+	}
+}